@@ -0,0 +1,123 @@
+// Package bus models the PlayStation controller bus at the byte level.
+//
+// Every frame starts with ATT going low followed by an address byte:
+// 0x01 for a controller, 0x81 for a memory card. Each byte is exchanged
+// in both directions at once, LSB first, and the addressed device pulses
+// ACK after every byte but the last to ask for the next one. Because a
+// device's reply is shifted out while the host's byte is shifted in, a
+// device always prepares its reply one byte ahead.
+package bus
+
+// Device addresses (first byte of a frame)
+const (
+	AddressController byte = 0x01
+	AddressMemoryCard byte = 0x81
+)
+
+// HiZ is the byte read while no device drives DAT (pulled up).
+const HiZ byte = 0xFF
+
+// Responder is the device side of the bus: a controller or a memory card.
+type Responder interface {
+	// Select is called when ATT goes low.
+	Select()
+
+	// Exchange is called with each byte received from the host. It returns
+	// the byte to shift out during the next exchange, and whether to pulse
+	// ACK to ask the host for it. A device that is not addressed, or has
+	// nothing more to say, returns false and stays silent until Deselect.
+	Exchange(cmd byte) (next byte, ack bool)
+
+	// Deselect is called when ATT goes high.
+	Deselect()
+}
+
+// Host is an in-process stand-in for the console. It drives devices
+// sharing one attention line, so device-side code can be exercised
+// without hardware. DAT is open drain, so the bytes of devices talking
+// at once are ANDed together.
+type Host struct {
+	slot slot
+}
+
+// NewHost creates a Host driving the given devices.
+func NewHost(devices ...Responder) *Host {
+	return &Host{slot: slot{devices: devices}}
+}
+
+// Transfer sends msg as one frame and stores the bytes read back in resp,
+// which must be at least as long as msg. Like the console it stops after
+// a byte that no device acknowledged. It returns the number of bytes
+// exchanged.
+func (h *Host) Transfer(msg []byte, resp []byte) int {
+	h.slot.selectAll()
+
+	n := 0
+	for n < len(msg) {
+		var ack bool
+		resp[n], ack = h.slot.exchange(msg[n])
+		n++
+
+		if !ack {
+			break
+		}
+	}
+
+	h.slot.deselectAll()
+	return n
+}
+
+// Replay sends each frame in order and returns the bytes read back.
+// Frames cut short by a missing ACK are truncated.
+func (h *Host) Replay(frames [][]byte) [][]byte {
+	out := make([][]byte, len(frames))
+	for i, msg := range frames {
+		resp := make([]byte, len(msg))
+		out[i] = resp[:h.Transfer(msg, resp)]
+	}
+	return out
+}
+
+// slot is the set of devices sharing one attention line.
+type slot struct {
+	devices []Responder
+	data    []byte
+}
+
+// selectAll pulls ATT low. Nobody drives DAT during the address byte.
+func (s *slot) selectAll() {
+	for _, d := range s.devices {
+		d.Select()
+	}
+	s.data = s.data[:0]
+	for range s.devices {
+		s.data = append(s.data, HiZ)
+	}
+}
+
+// exchange shifts one byte out to every device and returns the byte read
+// back, along with whether any device acknowledged it.
+func (s *slot) exchange(cmd byte) (byte, bool) {
+	in := HiZ
+	for _, b := range s.data {
+		in &= b
+	}
+
+	ack := false
+	for i, d := range s.devices {
+		next, a := d.Exchange(cmd)
+		if !a {
+			next = HiZ
+		}
+		s.data[i] = next
+		ack = ack || a
+	}
+	return in, ack
+}
+
+// deselectAll releases ATT.
+func (s *slot) deselectAll() {
+	for _, d := range s.devices {
+		d.Deselect()
+	}
+}
@@ -0,0 +1,191 @@
+package bus
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sample is the level of every bus line at one instant, as captured by a
+// logic analyzer or by polling pins. Lines idle high.
+type Sample struct {
+	Time time.Duration
+	ATT  bool
+	CLK  bool
+	CMD  bool
+	DAT  bool
+	ACK  bool
+}
+
+// Timing holds the limits a Decoder checks frames against.
+type Timing struct {
+	AttSetup  time.Duration // ATT low to first CLK fall
+	HalfCycle time.Duration // Shortest CLK high or low time
+}
+
+// DefaultTiming is loose enough for every console and card speed; a
+// capture that breaks it is almost certainly a wiring or sampling fault.
+var DefaultTiming = Timing{
+	AttSetup:  2 * time.Microsecond,
+	HalfCycle: 150 * time.Nanosecond,
+}
+
+// Violation is a protocol or timing fault found while decoding.
+type Violation struct {
+	Time    time.Duration
+	Byte    int // Index of the byte in its frame
+	Problem string
+}
+
+// String formats the violation for display.
+func (v Violation) String() string {
+	return fmt.Sprintf("%v byte %d: %s", v.Time, v.Byte, v.Problem)
+}
+
+// Decoder reassembles frames from line samples, the way a device sees
+// them: a frame runs while ATT is low, bits are read on the rising edge
+// of CLK, LSB first, on both CMD and DAT. Feed it the samples in time
+// order; a sample per line change is enough.
+type Decoder struct {
+	Timing Timing
+
+	// NoACK tells the decoder the capture has no ACK line, so missing
+	// ACK pulses are not reported.
+	NoACK bool
+
+	prev    Sample
+	primed  bool
+	active  bool
+	attFall time.Duration
+	clkEdge time.Duration // Last CLK edge in the frame
+	edges   int           // CLK edges seen in the frame
+	bits    int
+	cmd     byte
+	dat     byte
+	ackSeen bool // ACK pulsed since the last byte
+	fast    bool // Short half cycle already reported in the frame
+
+	frame      Frame
+	violations []Violation
+}
+
+// NewDecoder creates a Decoder checking DefaultTiming.
+func NewDecoder() *Decoder {
+	return &Decoder{Timing: DefaultTiming}
+}
+
+// Feed adds a sample. It returns true when ATT goes high and a frame is
+// complete; it is then available from Frame and Violations until the next
+// call to Feed.
+func (d *Decoder) Feed(s Sample) bool {
+	if !d.primed {
+		// The bus idles high before the first sample
+		d.prev = Sample{Time: s.Time, ATT: true, CLK: true, CMD: true, DAT: true, ACK: true}
+		d.primed = true
+	}
+	p := d.prev
+	d.prev = s
+
+	if p.ATT && !s.ATT {
+		d.begin(s.Time)
+		return false
+	}
+	if !d.active {
+		return false
+	}
+
+	if p.ACK && !s.ACK && len(d.frame.Ack) > 0 {
+		d.frame.Ack[len(d.frame.Ack)-1] = true
+		d.ackSeen = true
+	}
+
+	if p.CLK != s.CLK {
+		d.clock(s)
+	}
+
+	if p.ATT || !s.ATT {
+		return false
+	}
+	d.active = false
+	if d.bits != 0 {
+		d.violate(s.Time, fmt.Sprintf("frame ended after %d bits", d.bits))
+	}
+	return true
+}
+
+// Frame returns the last complete frame. Its slices are reused by later
+// frames.
+func (d *Decoder) Frame() Frame {
+	return d.frame
+}
+
+// Violations returns the faults found in the last complete frame.
+func (d *Decoder) Violations() []Violation {
+	return d.violations
+}
+
+// begin starts a frame when ATT goes low.
+func (d *Decoder) begin(t time.Duration) {
+	d.active = true
+	d.attFall = t
+	d.edges = 0
+	d.bits = 0
+	d.ackSeen = false
+	d.fast = false
+	d.frame.Time = t
+	d.frame.Cmd = d.frame.Cmd[:0]
+	d.frame.Resp = d.frame.Resp[:0]
+	d.frame.Ack = d.frame.Ack[:0]
+	d.violations = d.violations[:0]
+}
+
+// clock handles a CLK edge inside a frame.
+func (d *Decoder) clock(s Sample) {
+	if d.edges > 0 && s.Time-d.clkEdge < d.Timing.HalfCycle && !d.fast {
+		// Once per frame: a slow capture trips on every edge
+		d.fast = true
+		d.violate(s.Time, fmt.Sprintf("CLK half cycle %v", s.Time-d.clkEdge))
+	}
+	d.edges++
+	d.clkEdge = s.Time
+
+	if !s.CLK {
+		// Falling edge: the host starts shifting out a bit
+		if d.bits != 0 {
+			return
+		}
+		n := len(d.frame.Cmd)
+		if n == 0 && s.Time-d.attFall < d.Timing.AttSetup {
+			d.violate(s.Time, fmt.Sprintf("first clock %v after ATT", s.Time-d.attFall))
+		}
+		if n > 0 && !d.ackSeen && !d.NoACK {
+			d.violate(s.Time, "sent without ACK for the previous byte")
+		}
+		if n > 0 && !d.NoACK && !s.ACK {
+			d.violate(s.Time, "sent while ACK still low")
+		}
+		return
+	}
+
+	// Rising edge: both sides sample
+	d.cmd >>= 1
+	d.dat >>= 1
+	if s.CMD {
+		d.cmd |= 0x80
+	}
+	if s.DAT {
+		d.dat |= 0x80
+	}
+	d.bits++
+	if d.bits == 8 {
+		d.frame.Cmd = append(d.frame.Cmd, d.cmd)
+		d.frame.Resp = append(d.frame.Resp, d.dat)
+		d.frame.Ack = append(d.frame.Ack, false)
+		d.bits = 0
+		d.ackSeen = false
+	}
+}
+
+// violate records a fault against the byte in progress.
+func (d *Decoder) violate(t time.Duration, problem string) {
+	d.violations = append(d.violations, Violation{Time: t, Byte: len(d.frame.Cmd), Problem: problem})
+}
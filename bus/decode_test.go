@@ -0,0 +1,147 @@
+package bus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wave builds the samples of one frame at 250kHz, ACK pulsing after each
+// byte but the last unless ack says otherwise.
+type wave struct {
+	s       Sample
+	samples []Sample
+}
+
+func newWave() *wave {
+	return &wave{s: Sample{ATT: true, CLK: true, CMD: true, DAT: true, ACK: true}}
+}
+
+// at records the lines at time t after f changes them.
+func (w *wave) at(t time.Duration, f func(s *Sample)) {
+	f(&w.s)
+	w.s.Time = t
+	w.samples = append(w.samples, w.s)
+}
+
+func (w *wave) frame(start time.Duration, cmd, resp []byte, ack []bool) {
+	w.at(start, func(s *Sample) { s.ATT = false })
+	t := start + 10*time.Microsecond
+	for i := range cmd {
+		for bit := 0; bit < 8; bit++ {
+			w.at(t, func(s *Sample) {
+				s.CLK = false
+				s.CMD = cmd[i]>>bit&1 != 0
+				s.DAT = resp[i]>>bit&1 != 0
+			})
+			w.at(t+2*time.Microsecond, func(s *Sample) { s.CLK = true })
+			t += 4 * time.Microsecond
+		}
+		if ack[i] {
+			w.at(t+2*time.Microsecond, func(s *Sample) { s.ACK = false })
+			w.at(t+4*time.Microsecond, func(s *Sample) { s.ACK = true })
+		}
+		t += 10 * time.Microsecond
+	}
+	w.at(t, func(s *Sample) { s.ATT, s.CMD, s.DAT = true, true, true })
+}
+
+// decode feeds samples and returns the frames completed, with their
+// violations.
+func decode(d *Decoder, samples []Sample) ([]Frame, [][]Violation) {
+	var frames []Frame
+	var violations [][]Violation
+	for _, s := range samples {
+		if d.Feed(s) {
+			f := d.Frame()
+			frames = append(frames, Frame{
+				Time: f.Time,
+				Cmd:  append([]byte(nil), f.Cmd...),
+				Resp: append([]byte(nil), f.Resp...),
+				Ack:  append([]bool(nil), f.Ack...),
+			})
+			violations = append(violations, append([]Violation(nil), d.Violations()...))
+		}
+	}
+	return frames, violations
+}
+
+func TestDecoderFrame(t *testing.T) {
+	w := newWave()
+	cmd := []byte{0x01, 0x42, 0x00, 0x00, 0x00}
+	resp := []byte{0xFF, 0x41, 0x5A, 0xFE, 0xFF}
+	w.frame(100*time.Microsecond, cmd, resp, []bool{true, true, true, true, false})
+
+	frames, violations := decode(NewDecoder(), w.samples)
+	if len(frames) != 1 {
+		t.Fatalf("decoded %d frames, want 1", len(frames))
+	}
+	f := frames[0]
+	if f.Time != 100*time.Microsecond || !bytes.Equal(f.Cmd, cmd) || !bytes.Equal(f.Resp, resp) {
+		t.Errorf("frame %v CMD % X DAT % X", f.Time, f.Cmd, f.Resp)
+	}
+	if !f.Ack[3] || f.Ack[4] {
+		t.Errorf("ACK %v, want all but the last", f.Ack)
+	}
+	if len(violations[0]) != 0 {
+		t.Errorf("violations %v, want none", violations[0])
+	}
+}
+
+func TestDecoderMissingACK(t *testing.T) {
+	w := newWave()
+	w.frame(0, []byte{0x01, 0x42, 0x00}, []byte{0xFF, 0x41, 0x5A}, []bool{true, false, false})
+
+	_, violations := decode(NewDecoder(), w.samples)
+	if len(violations) != 1 || len(violations[0]) != 1 {
+		t.Fatalf("violations %v, want one", violations)
+	}
+	if v := violations[0][0]; v.Byte != 2 || !strings.Contains(v.Problem, "without ACK") {
+		t.Errorf("violation %v, want byte 2 sent without ACK", v)
+	}
+
+	// Without an ACK channel the same capture is clean
+	d := NewDecoder()
+	d.NoACK = true
+	if _, violations = decode(d, w.samples); len(violations[0]) != 0 {
+		t.Errorf("violations %v with NoACK", violations[0])
+	}
+}
+
+func TestDecoderTiming(t *testing.T) {
+	w := newWave()
+	w.frame(0, []byte{0x01}, []byte{0xFF}, []bool{false})
+
+	// Halve every time: the 5us ATT setup is still fine, the 1us half
+	// cycles are not
+	d := NewDecoder()
+	d.Timing.HalfCycle = 1500 * time.Nanosecond
+	for i := range w.samples {
+		w.samples[i].Time /= 2
+	}
+	_, violations := decode(d, w.samples)
+	if len(violations[0]) != 1 || !strings.Contains(violations[0][0].Problem, "half cycle") {
+		t.Errorf("violations %v, want one short half cycle", violations[0])
+	}
+}
+
+func TestDecoderShortFrame(t *testing.T) {
+	w := newWave()
+	w.frame(0, []byte{0x01}, []byte{0xFF}, []bool{false})
+
+	// Cut the frame after three bits
+	cut := append([]Sample(nil), w.samples[:1+6]...)
+	end := cut[len(cut)-1]
+	end.Time += 10 * time.Microsecond
+	end.ATT = true
+	cut = append(cut, end)
+
+	frames, violations := decode(NewDecoder(), cut)
+	if len(frames) != 1 || len(frames[0].Cmd) != 0 {
+		t.Fatalf("frames %v, want one empty frame", frames)
+	}
+	if len(violations[0]) != 1 || !strings.Contains(violations[0][0].Problem, "after 3 bits") {
+		t.Errorf("violations %v, want the frame ending after 3 bits", violations[0])
+	}
+}
@@ -0,0 +1,104 @@
+package bus
+
+import "fmt"
+
+// Describe names the command carried by a frame and its main parameters,
+// for logs and decoders. It knows controller commands and PS1 and PS2
+// memory card commands; anything else is described by its first bytes.
+func Describe(f Frame) string {
+	if len(f.Cmd) < 2 {
+		return "empty frame"
+	}
+	switch f.Cmd[0] {
+	case AddressController:
+		return describeController(f)
+	case AddressMemoryCard:
+		return describeMemoryCard(f)
+	}
+	return fmt.Sprintf("unknown address %02X", f.Cmd[0])
+}
+
+// param returns byte i of a frame, or 0 past its end.
+func param(b []byte, i int) byte {
+	if i < len(b) {
+		return b[i]
+	}
+	return 0
+}
+
+// describeController describes a controller command.
+func describeController(f Frame) string {
+	id := param(f.Resp, 1)
+	switch cmd, p := f.Cmd[1], param(f.Cmd, 3); cmd {
+	case 0x42:
+		return fmt.Sprintf("poll (id %02X) motors %02X %02X", id, p, param(f.Cmd, 4))
+	case 0x43:
+		if id == 0xF3 {
+			// Already in config mode: this is the exit (or a no-op enter)
+			if p == 0x00 {
+				return "config exit"
+			}
+			return "config enter (already in config)"
+		}
+		if p == 0x01 {
+			return fmt.Sprintf("config enter (id %02X)", id)
+		}
+		return fmt.Sprintf("poll via config (id %02X)", id)
+	case 0x44:
+		mode := "digital"
+		if p == 0x01 {
+			mode = "analog"
+		}
+		lock := "unlocked"
+		if param(f.Cmd, 4) == 0x03 {
+			lock = "locked"
+		}
+		return "set mode " + mode + ", " + lock
+	case 0x45:
+		return fmt.Sprintf("status: type %02X, analog %v", param(f.Resp, 3), param(f.Resp, 5) == 0x01)
+	case 0x41:
+		return fmt.Sprintf("query pressure mask: % X", f.Resp[min(3, len(f.Resp)):])
+	case 0x46, 0x47, 0x4C:
+		return fmt.Sprintf("query constant %02X(%02X)", cmd, p)
+	case 0x4D:
+		return fmt.Sprintf("motor map % X", f.Cmd[min(3, len(f.Cmd)):])
+	case 0x4F:
+		return fmt.Sprintf("set pressure mask %02X %02X %02X", p, param(f.Cmd, 4), param(f.Cmd, 5))
+	}
+	return fmt.Sprintf("controller command %02X", f.Cmd[1])
+}
+
+// describeMemoryCard describes a PS1 or PS2 memory card command.
+func describeMemoryCard(f Frame) string {
+	switch cmd := f.Cmd[1]; cmd {
+	case 0x52, 0x57:
+		op := "read"
+		if cmd == 0x57 {
+			op = "write"
+		}
+		frame := uint16(param(f.Cmd, 4))<<8 | uint16(param(f.Cmd, 5))
+		return fmt.Sprintf("PS1 card %s frame %d, end %02X", op, frame, f.Resp[len(f.Resp)-1])
+	case 0x53:
+		return "PS1 card get ID"
+	case 0x11:
+		return "PS2 card probe"
+	case 0x12:
+		return "PS2 card write end"
+	case 0x21, 0x22, 0x23:
+		op := [...]string{"erase", "write", "read"}[cmd-0x21]
+		page := uint32(param(f.Cmd, 2)) | uint32(param(f.Cmd, 3))<<8 |
+			uint32(param(f.Cmd, 4))<<16 | uint32(param(f.Cmd, 5))<<24
+		return fmt.Sprintf("PS2 card set %s address %d", op, page)
+	case 0x26:
+		return "PS2 card get specs"
+	case 0x42:
+		return fmt.Sprintf("PS2 card write data, %d bytes", param(f.Cmd, 2))
+	case 0x43:
+		return fmt.Sprintf("PS2 card read data, %d bytes", param(f.Cmd, 2))
+	case 0x81:
+		return "PS2 card read/write end"
+	case 0x82:
+		return "PS2 card erase"
+	}
+	return fmt.Sprintf("memory card command %02X", f.Cmd[1])
+}
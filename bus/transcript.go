@@ -0,0 +1,302 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Transcript format: the magic string, then one record per frame:
+//
+//	flags     1 byte, bit 0 = pad
+//	delta     uvarint, microseconds since the previous frame began
+//	n         uvarint, number of bytes exchanged
+//	cmd       n bytes sent by the host
+//	resp      n bytes read back
+//	ack       (n+7)/8 bytes, bit i set if byte i was acknowledged
+const transcriptMagic = "GPSXT1"
+
+// maxTranscriptFrame bounds the frame length accepted when reading, so a
+// corrupt transcript cannot ask for a huge buffer.
+const maxTranscriptFrame = 1024
+
+// Transcript errors
+var (
+	ErrTranscriptFormat = errors.New("bus: not a transcript")
+	ErrReplayDiverged   = errors.New("bus: driver diverged from transcript")
+)
+
+// Frame is one recorded frame: everything between ATT going low and high.
+type Frame struct {
+	Pad  uint8
+	Time time.Duration // Since the start of the recording
+	Cmd  []byte        // Bytes sent by the host
+	Resp []byte        // Bytes read back
+	Ack  []bool        // Whether each byte was acknowledged
+}
+
+// Recorder is a Transport that passes everything through to another one
+// and writes each frame to w as it ends.
+type Recorder struct {
+	t     Transport
+	w     io.Writer
+	err   error
+	start time.Time
+	began time.Time
+	last  time.Duration
+	magic bool // Magic string written
+
+	frame Frame
+	buf   []byte
+}
+
+// NewRecorder creates a Recorder writing frames exchanged on t to w.
+func NewRecorder(t Transport, w io.Writer) *Recorder {
+	return &Recorder{t: t, w: w}
+}
+
+// Transport returns the Transport being recorded.
+func (r *Recorder) Transport() Transport {
+	return r.t
+}
+
+// Err returns the first error from writing the transcript. Frames after
+// an error are still passed through but no longer recorded.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+// Begin implements Transport.
+func (r *Recorder) Begin(pad uint8) {
+	r.began = time.Now()
+	if r.start.IsZero() {
+		r.start = r.began
+	}
+	r.frame.Pad = pad
+	r.frame.Cmd = r.frame.Cmd[:0]
+	r.frame.Resp = r.frame.Resp[:0]
+	r.frame.Ack = r.frame.Ack[:0]
+	r.t.Begin(pad)
+}
+
+// Transfer implements Transport.
+func (r *Recorder) Transfer(cmd byte) byte {
+	in := r.t.Transfer(cmd)
+	r.frame.Cmd = append(r.frame.Cmd, cmd)
+	r.frame.Resp = append(r.frame.Resp, in)
+	r.frame.Ack = append(r.frame.Ack, false)
+	return in
+}
+
+// WaitAck implements Transport.
+func (r *Recorder) WaitAck(timeout time.Duration) bool {
+	ack := r.t.WaitAck(timeout)
+	if n := len(r.frame.Ack); n > 0 {
+		r.frame.Ack[n-1] = ack
+	}
+	return ack
+}
+
+// End implements Transport.
+func (r *Recorder) End(pad uint8) {
+	r.t.End(pad)
+	if r.err != nil {
+		return
+	}
+
+	r.buf = r.buf[:0]
+	if !r.magic {
+		r.buf = append(r.buf, transcriptMagic...)
+		r.magic = true
+	}
+	r.frame.Time = r.began.Sub(r.start)
+	r.buf = appendFrame(r.buf, &r.frame, r.frame.Time-r.last)
+	r.last = r.frame.Time
+	_, r.err = r.w.Write(r.buf)
+}
+
+// appendFrame encodes f, whose time is delta after the previous frame.
+func appendFrame(buf []byte, f *Frame, delta time.Duration) []byte {
+	buf = append(buf, f.Pad&1)
+	buf = binary.AppendUvarint(buf, uint64(delta/time.Microsecond))
+	buf = binary.AppendUvarint(buf, uint64(len(f.Cmd)))
+	buf = append(buf, f.Cmd...)
+	buf = append(buf, f.Resp...)
+
+	var bits byte
+	for i, ack := range f.Ack {
+		if ack {
+			bits |= 1 << (i % 8)
+		}
+		if i%8 == 7 || i == len(f.Ack)-1 {
+			buf = append(buf, bits)
+			bits = 0
+		}
+	}
+	return buf
+}
+
+// TranscriptReader reads frames from a transcript one at a time.
+type TranscriptReader struct {
+	r     *bufio.Reader
+	magic bool
+	time  time.Duration
+}
+
+// NewTranscriptReader creates a TranscriptReader reading from r.
+func NewTranscriptReader(r io.Reader) *TranscriptReader {
+	return &TranscriptReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next frame. It returns io.EOF after the last one.
+func (t *TranscriptReader) Next() (Frame, error) {
+	var f Frame
+	if !t.magic {
+		var magic [len(transcriptMagic)]byte
+		if _, err := io.ReadFull(t.r, magic[:]); err != nil {
+			if err == io.EOF {
+				return f, io.EOF
+			}
+			return f, ErrTranscriptFormat
+		}
+		if string(magic[:]) != transcriptMagic {
+			return f, ErrTranscriptFormat
+		}
+		t.magic = true
+	}
+
+	flags, err := t.r.ReadByte()
+	if err != nil {
+		return f, err
+	}
+	delta, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return f, io.ErrUnexpectedEOF
+	}
+	n, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return f, io.ErrUnexpectedEOF
+	}
+	if n > maxTranscriptFrame {
+		return f, ErrTranscriptFormat
+	}
+
+	t.time += time.Duration(delta) * time.Microsecond
+	f.Pad = flags & 1
+	f.Time = t.time
+
+	data := make([]byte, 2*n+(n+7)/8)
+	if _, err := io.ReadFull(t.r, data); err != nil {
+		return f, io.ErrUnexpectedEOF
+	}
+	f.Cmd = data[:n]
+	f.Resp = data[n : 2*n]
+	f.Ack = make([]bool, n)
+	for i := range f.Ack {
+		f.Ack[i] = data[2*n+uint64(i)/8]&(1<<(i%8)) != 0
+	}
+	return f, nil
+}
+
+// ReadTranscript reads a whole transcript.
+func ReadTranscript(r io.Reader) ([]Frame, error) {
+	tr := NewTranscriptReader(r)
+	var frames []Frame
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+}
+
+// Replay is a Transport that answers from a recorded transcript, so
+// driver behaviour can be reproduced away from the hardware. Each Begin
+// takes the next frame; the host is expected to send the same bytes as
+// when it was recorded. Past the end of the transcript nothing answers.
+type Replay struct {
+	frames []Frame
+	next   int
+	frame  *Frame
+	pos    int
+	err    error
+	at     time.Duration // Time of the last frame begun
+}
+
+// NewReplay creates a Replay answering with frames.
+func NewReplay(frames []Frame) *Replay {
+	return &Replay{frames: frames}
+}
+
+// Done reports whether every frame has been replayed.
+func (r *Replay) Done() bool {
+	return r.next >= len(r.frames)
+}
+
+// Err returns ErrReplayDiverged if the host sent something other than
+// what was recorded, or nil.
+func (r *Replay) Err() error {
+	return r.err
+}
+
+// Clock returns a time source reading start plus the recorded time of
+// the last frame begun, for GPSX.SetClock. Code timing polls then sees
+// the gaps of the recording rather than how fast it is replayed.
+func (r *Replay) Clock(start time.Time) func() time.Time {
+	return func() time.Time {
+		return start.Add(r.at)
+	}
+}
+
+// Begin implements Transport.
+func (r *Replay) Begin(pad uint8) {
+	r.frame = nil
+	r.pos = 0
+	if r.next >= len(r.frames) {
+		return
+	}
+	r.frame = &r.frames[r.next]
+	r.next++
+	r.at = r.frame.Time
+	if r.frame.Pad != pad&1 {
+		r.err = ErrReplayDiverged
+	}
+}
+
+// Transfer implements Transport.
+func (r *Replay) Transfer(cmd byte) byte {
+	if r.frame == nil || r.pos >= len(r.frame.Cmd) {
+		if r.frame != nil {
+			r.err = ErrReplayDiverged
+		}
+		return HiZ
+	}
+	if r.frame.Cmd[r.pos] != cmd {
+		r.err = ErrReplayDiverged
+	}
+	in := r.frame.Resp[r.pos]
+	r.pos++
+	return in
+}
+
+// WaitAck implements Transport.
+func (r *Replay) WaitAck(timeout time.Duration) bool {
+	if r.frame == nil || r.pos == 0 || r.pos > len(r.frame.Ack) {
+		return false
+	}
+	return r.frame.Ack[r.pos-1]
+}
+
+// End implements Transport.
+func (r *Replay) End(pad uint8) {
+	if r.frame != nil && r.pos != len(r.frame.Cmd) {
+		r.err = ErrReplayDiverged
+	}
+	r.frame = nil
+}
@@ -0,0 +1,73 @@
+package bus
+
+import "time"
+
+// Transport is the host side of the bus as seen by the driver: it selects
+// a slot and exchanges bytes with whatever is plugged into it. The driver
+// talks to real pins through one, and to in-process devices through a
+// Loopback.
+type Transport interface {
+	// Begin pulls ATT low for the slot.
+	Begin(pad uint8)
+
+	// Transfer exchanges one byte and returns the byte read back.
+	Transfer(cmd byte) byte
+
+	// WaitAck waits for the device to acknowledge the last byte. It
+	// returns false if no ACK arrived within timeout.
+	WaitAck(timeout time.Duration) bool
+
+	// End releases ATT for the slot.
+	End(pad uint8)
+}
+
+// Loopback is a Transport wired straight to Responders, one set per slot.
+// It lets the driver run against simulated pads and memory cards with no
+// hardware and no timing.
+type Loopback struct {
+	slots   [2]slot
+	current *slot
+	ack     bool
+}
+
+// NewLoopback creates a Loopback with nothing plugged in.
+func NewLoopback() *Loopback {
+	return &Loopback{}
+}
+
+// Attach plugs r into the slot for pad (0 or 1). A controller and a memory
+// card can share a slot, as they do on the console.
+func (l *Loopback) Attach(pad uint8, r Responder) {
+	l.slots[pad&1].devices = append(l.slots[pad&1].devices, r)
+}
+
+// Begin implements Transport.
+func (l *Loopback) Begin(pad uint8) {
+	l.current = &l.slots[pad&1]
+	l.current.selectAll()
+	l.ack = false
+}
+
+// Transfer implements Transport.
+func (l *Loopback) Transfer(cmd byte) byte {
+	if l.current == nil {
+		return HiZ
+	}
+	var in byte
+	in, l.ack = l.current.exchange(cmd)
+	return in
+}
+
+// WaitAck implements Transport. The reply to the last byte is already
+// known, so it never waits.
+func (l *Loopback) WaitAck(timeout time.Duration) bool {
+	return l.ack
+}
+
+// End implements Transport.
+func (l *Loopback) End(pad uint8) {
+	if l.current != nil {
+		l.current.deselectAll()
+		l.current = nil
+	}
+}
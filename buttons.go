@@ -27,6 +27,10 @@ var (
 	ButtonL1       = Button{4, 1 << 2}
 	ButtonR2       = Button{4, 1 << 1}
 	ButtonL2       = Button{4, 1 << 0}
+
+	// PS1 Analog Joystick stick buttons (reported on the L3/R3 bits)
+	ButtonJoyLeft  = ButtonStickLeft
+	ButtonJoyRight = ButtonStickRight
 )
 
 // IsDown returns true if the button is currently pressed.
@@ -35,6 +39,22 @@ func (g *GPSX) IsDown(pad uint8, btn Button) bool {
 	return g.keyState[pad][stateCurrent][btn.byteIndex]&btn.bitMask == 0
 }
 
+// SetDown marks the button as pressed or released in a raw poll response
+// (active LOW). It is the inverse of IsDown, for code answering polls.
+func (btn Button) SetDown(resp []byte, down bool) {
+	if down {
+		resp[btn.byteIndex] &^= btn.bitMask
+	} else {
+		resp[btn.byteIndex] |= btn.bitMask
+	}
+}
+
+// Down reports whether the button is pressed in a raw poll response,
+// such as a frame recorded by a Sniffer.
+func (btn Button) Down(resp []byte) bool {
+	return int(btn.byteIndex) < len(resp) && resp[btn.byteIndex]&btn.bitMask == 0
+}
+
 // Pressed returns true if the button was just pressed (transition from up to down).
 // This uses edge detection and only returns true once per button press.
 func (g *GPSX) Pressed(pad uint8, btn Button) bool {
@@ -80,11 +100,24 @@ func (g *GPSX) AnalogLeftY(pad uint8) uint8 {
 }
 
 // IsAnalog returns true if the controller is in analog mode.
+// The PS1 Analog Joystick is reported as analog, since its four axes
+// use the same bytes as the analog sticks.
 func (g *GPSX) IsAnalog(pad uint8) bool {
-	return g.keyState[pad][stateCurrent][1]&0xF0 == 0x70
+	return isAnalogID(g.keyState[pad][stateCurrent][1])
+}
+
+// IsFlightStick returns true if the controller is a PS1 Analog Joystick
+// (SCPH-1110) with its mode switch set to the joystick position.
+func (g *GPSX) IsFlightStick(pad uint8) bool {
+	return g.keyState[pad][stateCurrent][1] == DeviceFlightStick
+}
+
+// DeviceID returns the device ID reported on the last poll.
+func (g *GPSX) DeviceID(pad uint8) uint8 {
+	return g.keyState[pad][stateCurrent][1]
 }
 
 // IsDigital returns true if the controller is in digital mode.
 func (g *GPSX) IsDigital(pad uint8) bool {
-	return g.keyState[pad][stateCurrent][1]&0xF0 == 0x40
+	return g.keyState[pad][stateCurrent][1]&0xF0 == DeviceDigital&0xF0
 }
@@ -0,0 +1,200 @@
+package gpsx
+
+import (
+	"errors"
+	"time"
+)
+
+// Axis selects a stick axis, in poll response order.
+type Axis uint8
+
+// Axes
+const (
+	AxisRightX Axis = iota
+	AxisRightY
+	AxisLeftX
+	AxisLeftY
+)
+
+// CalibrationSize is the length of a serialized Calibration.
+const CalibrationSize = 16
+
+// calibrationMagic starts a serialized Calibration, with its version.
+const calibrationMagic = "GC\x01"
+
+// minCalibrationRange is the smallest travel either side of the centre
+// a sweep must reach for the calibration to be accepted.
+const minCalibrationRange = 32
+
+// Calibration errors
+var (
+	ErrCalibration      = errors.New("gpsx: invalid calibration record")
+	ErrCalibrationRange = errors.New("gpsx: stick not moved far enough during calibration")
+)
+
+// AxisCalibration is the measured travel of one axis.
+type AxisCalibration struct {
+	Min    uint8
+	Centre uint8
+	Max    uint8
+}
+
+// Calibration maps the raw stick values of a pad onto the full 0-255
+// range with the rest position at 0x80. The zero value leaves the values
+// untouched.
+type Calibration struct {
+	Axes [4]AxisCalibration // Indexed by Axis
+}
+
+// valid reports whether the calibration can be applied.
+func (c *Calibration) valid() bool {
+	for _, a := range c.Axes {
+		if !(a.Min < a.Centre && a.Centre < a.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply maps a raw value of axis a.
+func (a AxisCalibration) apply(v uint8) uint8 {
+	if v <= a.Min {
+		return 0x00
+	}
+	if v >= a.Max {
+		return 0xFF
+	}
+	if v < a.Centre {
+		return uint8(0x80 - int(a.Centre-v)*0x80/int(a.Centre-a.Min))
+	}
+	return uint8(0x80 + int(v-a.Centre)*0x7F/int(a.Max-a.Centre))
+}
+
+// MarshalBinary encodes the calibration in CalibrationSize bytes, for
+// firmware to keep in flash.
+func (c Calibration) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, CalibrationSize)
+	b = append(b, calibrationMagic...)
+	for _, a := range c.Axes {
+		b = append(b, a.Min, a.Centre, a.Max)
+	}
+	return append(b, xor(b)), nil
+}
+
+// UnmarshalBinary decodes a calibration written by MarshalBinary. The
+// zero Calibration, calibration off, is accepted like a measured one.
+func (c *Calibration) UnmarshalBinary(b []byte) error {
+	if len(b) != CalibrationSize || string(b[:len(calibrationMagic)]) != calibrationMagic ||
+		xor(b[:CalibrationSize-1]) != b[CalibrationSize-1] {
+		return ErrCalibration
+	}
+	var cal Calibration
+	p := b[len(calibrationMagic):]
+	for i := range cal.Axes {
+		cal.Axes[i] = AxisCalibration{Min: p[3*i], Centre: p[3*i+1], Max: p[3*i+2]}
+	}
+	if cal != (Calibration{}) && !cal.valid() {
+		return ErrCalibration
+	}
+	*c = cal
+	return nil
+}
+
+// SetCalibration applies cal to every stick value of pad read from the
+// next UpdateState on: AnalogLeftX and friends, State and Stick. An
+// invalid or zero Calibration turns calibration off.
+func (g *GPSX) SetCalibration(pad uint8, cal Calibration) {
+	if !cal.valid() {
+		cal = Calibration{}
+	}
+	g.calibration[pad] = cal
+}
+
+// Calibration returns the calibration applied to pad.
+func (g *GPSX) Calibration(pad uint8) Calibration {
+	return g.calibration[pad]
+}
+
+// calibrate stores the raw stick values of the last poll and replaces
+// them with drift-corrected, calibrated ones.
+func (g *GPSX) calibrate(pad uint8, now time.Time) {
+	k := &g.keyState[pad][stateCurrent]
+	if !isAnalogID(k[1]) {
+		return
+	}
+	copy(g.rawSticks[pad][:], k[5:9])
+
+	cal := &g.calibration[pad]
+	centre := [4]uint8{0x80, 0x80, 0x80, 0x80}
+	if cal.valid() {
+		for i, a := range cal.Axes {
+			centre[i] = a.Centre
+		}
+	}
+	g.drift[pad].track(&g.rawSticks[pad], &centre, now)
+	g.drift[pad].correct(k[5:9])
+
+	if !cal.valid() {
+		return
+	}
+	for i, a := range cal.Axes {
+		k[5+i] = a.apply(k[5+i])
+	}
+}
+
+// Calibrator measures a Calibration for a pad. Poll the pad as usual and
+// call Rest after each poll while the sticks are left alone, then Sweep
+// after each poll while the user rolls both sticks round their full
+// travel a few times.
+type Calibrator struct {
+	g   *GPSX
+	pad uint8
+
+	sum  [4]uint32
+	rest uint32
+	min  [4]uint8
+	max  [4]uint8
+}
+
+// Calibrator returns a new Calibrator for pad.
+func (g *GPSX) Calibrator(pad uint8) *Calibrator {
+	c := &Calibrator{g: g, pad: pad}
+	for i := range c.min {
+		c.min[i] = 0xFF
+	}
+	return c
+}
+
+// Rest adds the last poll to the centre estimate.
+func (c *Calibrator) Rest() {
+	for i, v := range c.g.rawSticks[c.pad] {
+		c.sum[i] += uint32(v)
+	}
+	c.rest++
+}
+
+// Sweep adds the last poll to the travel measured.
+func (c *Calibrator) Sweep() {
+	for i, v := range c.g.rawSticks[c.pad] {
+		c.min[i] = min(c.min[i], v)
+		c.max[i] = max(c.max[i], v)
+	}
+}
+
+// Result returns the measured calibration. It returns ErrCalibrationRange
+// if no rest position was taken or an axis did not travel at least a
+// quarter of its range either side of the centre.
+func (c *Calibrator) Result() (Calibration, error) {
+	var cal Calibration
+	if c.rest == 0 {
+		return cal, ErrCalibrationRange
+	}
+	for i := range cal.Axes {
+		centre := uint8((c.sum[i] + c.rest/2) / c.rest)
+		if int(centre)-int(c.min[i]) < minCalibrationRange || int(c.max[i])-int(centre) < minCalibrationRange {
+			return Calibration{}, ErrCalibrationRange
+		}
+		cal.Axes[i] = AxisCalibration{Min: c.min[i], Centre: centre, Max: c.max[i]}
+	}
+	return cal, nil
+}
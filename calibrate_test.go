@@ -0,0 +1,132 @@
+package gpsx_test
+
+import (
+	"testing"
+
+	"gpsx"
+	"gpsx/sim"
+)
+
+var testCalibration = gpsx.Calibration{Axes: [4]gpsx.AxisCalibration{
+	{Min: 0x10, Centre: 0x7C, Max: 0xF0},
+	{Min: 0x20, Centre: 0x84, Max: 0xE8},
+	{Min: 0x08, Centre: 0x80, Max: 0xF8},
+	{Min: 0x18, Centre: 0x78, Max: 0xE0},
+}}
+
+func TestCalibrationRoundTrip(t *testing.T) {
+	for _, cal := range []gpsx.Calibration{testCalibration, {}} {
+		b, err := cal.MarshalBinary()
+		if err != nil || len(b) != gpsx.CalibrationSize {
+			t.Fatalf("MarshalBinary: %d bytes, %v", len(b), err)
+		}
+		var got gpsx.Calibration
+		got.Axes[0].Max = 0xFF
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Errorf("UnmarshalBinary of %v: %v", cal, err)
+		}
+		if got != cal {
+			t.Errorf("round trip gave %v, want %v", got, cal)
+		}
+	}
+}
+
+func TestCalibrationCorrupt(t *testing.T) {
+	good, _ := testCalibration.MarshalBinary()
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"checksum", func(b []byte) []byte { b[len(b)-1] ^= 0x01; return b }},
+		{"data", func(b []byte) []byte { b[5] ^= 0x40; return b }},
+		{"magic", func(b []byte) []byte { b[0] = 'X'; b[len(b)-1] ^= 'X' ^ 'G'; return b }},
+		{"version", func(b []byte) []byte { b[2] = 2; b[len(b)-1] ^= 2 ^ 1; return b }},
+		{"short", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"centre outside travel", func(b []byte) []byte {
+			// Min above Centre on the first axis, with a good checksum
+			b[3], b[4] = b[4], b[3]
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		b := tt.modify(append([]byte(nil), good...))
+		cal := testCalibration
+		if err := cal.UnmarshalBinary(b); err != gpsx.ErrCalibration {
+			t.Errorf("%s: %v, want %v", tt.name, err, gpsx.ErrCalibration)
+		}
+		if cal != testCalibration {
+			t.Errorf("%s: calibration changed by a failed decode", tt.name)
+		}
+	}
+}
+
+func TestCalibrationApplied(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+	s.PSX.SetCalibration(gpsx.Pad1, testCalibration)
+
+	s.Sticks(gpsx.Pad1, 0x7C, 0xE8, 0x08, 0x00)
+	s.Poll(gpsx.Pad1)
+	got := [4]uint8{
+		s.PSX.AnalogRightX(gpsx.Pad1), s.PSX.AnalogRightY(gpsx.Pad1),
+		s.PSX.AnalogLeftX(gpsx.Pad1), s.PSX.AnalogLeftY(gpsx.Pad1),
+	}
+	if want := [4]uint8{0x80, 0xFF, 0x00, 0x00}; got != want {
+		t.Errorf("calibrated sticks % X, want % X", got, want)
+	}
+
+	// An invalid calibration turns it off
+	s.PSX.SetCalibration(gpsx.Pad1, gpsx.Calibration{Axes: [4]gpsx.AxisCalibration{{Min: 5}}})
+	if s.PSX.Calibration(gpsx.Pad1) != (gpsx.Calibration{}) {
+		t.Error("invalid calibration kept")
+	}
+}
+
+func TestCalibrator(t *testing.T) {
+	sweep := func(s *sim.Sim, c *gpsx.Calibrator, lo, hi uint8) {
+		for _, v := range []uint8{lo, hi} {
+			s.Sticks(gpsx.Pad1, v, v, v, v)
+			s.Poll(gpsx.Pad1)
+			c.Sweep()
+		}
+	}
+	newSim := func() *sim.Sim {
+		s := sim.New(gpsx.PS2)
+		s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+		return s
+	}
+
+	// No rest position
+	s := newSim()
+	c := s.PSX.Calibrator(gpsx.Pad1)
+	sweep(s, c, 0x00, 0xFF)
+	if _, err := c.Result(); err != gpsx.ErrCalibrationRange {
+		t.Errorf("no rest: %v, want %v", err, gpsx.ErrCalibrationRange)
+	}
+
+	// Rest averages to 0x7E
+	s = newSim()
+	c = s.PSX.Calibrator(gpsx.Pad1)
+	for _, v := range []uint8{0x7D, 0x7F, 0x7E} {
+		s.Sticks(gpsx.Pad1, v, v, v, v)
+		s.Poll(gpsx.Pad1)
+		c.Rest()
+	}
+	sweep(s, c, 0x7E-31, 0xF0)
+	if _, err := c.Result(); err != gpsx.ErrCalibrationRange {
+		t.Errorf("short sweep: %v, want %v", err, gpsx.ErrCalibrationRange)
+	}
+
+	sweep(s, c, 0x0C, 0xF4)
+	cal, err := c.Result()
+	if err != nil {
+		t.Fatalf("full sweep: %v", err)
+	}
+	want := gpsx.AxisCalibration{Min: 0x0C, Centre: 0x7E, Max: 0xF4}
+	for i, a := range cal.Axes {
+		if a != want {
+			t.Errorf("axis %d: %+v, want %+v", i, a, want)
+		}
+	}
+}
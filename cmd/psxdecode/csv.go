@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpsx/bus"
+)
+
+// readCSV reads a Saleae Logic export: a header row naming the time
+// column and the channels, then one row per change with the time in
+// seconds and each channel as 0 or 1.
+func readCSV(r io.Reader, names [numLines]string, header headerFunc, feed sampleFunc) error {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	head, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("csv: %v", err)
+	}
+	var cols [numLines]int
+	var found [numLines]bool
+	for c, h := range head {
+		for i, n := range names {
+			if strings.TrimSpace(h) == n {
+				cols[i] = c
+				found[i] = true
+			}
+		}
+	}
+	if err := missing(names, found); err != nil {
+		return err
+	}
+	header(found[lineACK])
+
+	s := bus.Sample{ACK: true}
+	for row := 2; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("csv: %v", err)
+		}
+
+		secs, err := strconv.ParseFloat(rec[0], 64)
+		if err != nil {
+			return fmt.Errorf("csv: row %d: bad time %q", row, rec[0])
+		}
+		s.Time = time.Duration(secs * float64(time.Second))
+		for i := range cols {
+			if found[i] {
+				setLine(&s, i, strings.TrimSpace(rec[cols[i]]) != "0")
+			}
+		}
+		feed(s)
+	}
+}
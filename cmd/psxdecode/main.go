@@ -0,0 +1,127 @@
+// Command psxdecode decodes logic analyzer captures of the controller bus.
+//
+// It reads a sigrok/PulseView VCD export or a Saleae Logic CSV export
+// holding ATT, CLK, CMD, DAT and optionally ACK, rebuilds the bytes with
+// the library's bus.Decoder and prints each frame with its command named
+// and any timing faults flagged:
+//
+//	psxdecode capture.vcd
+//	psxdecode -att D3 -clk D2 -cmd D1 -dat D0 capture.csv
+//
+// Channels are matched by name; use the flags when the capture does not
+// name them ATT, CLK, CMD, DAT and ACK.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gpsx/bus"
+)
+
+// channel indices into a line set
+const (
+	lineATT = iota
+	lineCLK
+	lineCMD
+	lineDAT
+	lineACK
+	numLines
+)
+
+// sampleFunc receives the line levels after each change.
+type sampleFunc func(bus.Sample)
+
+// headerFunc is told, once the channels are known, whether ACK was
+// captured.
+type headerFunc func(hasACK bool)
+
+func main() {
+	var names [numLines]string
+	flag.StringVar(&names[lineATT], "att", "ATT", "ATT channel name")
+	flag.StringVar(&names[lineCLK], "clk", "CLK", "CLK channel name")
+	flag.StringVar(&names[lineCMD], "cmd", "CMD", "CMD channel name")
+	flag.StringVar(&names[lineDAT], "dat", "DAT", "DAT channel name")
+	flag.StringVar(&names[lineACK], "ack", "ACK", "ACK channel name (optional)")
+	format := flag.String("format", "", "capture format: vcd or csv (default: from the file extension)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: psxdecode [flags] capture.{vcd,csv}\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	d := bus.NewDecoder()
+	frames, faults := 0, 0
+	feed := func(s bus.Sample) {
+		if !d.Feed(s) {
+			return
+		}
+		frames++
+		faults += len(d.Violations())
+		printFrame(d.Frame(), d.Violations())
+	}
+
+	header := func(hasACK bool) {
+		d.NoACK = !hasACK
+		if !hasACK {
+			fmt.Println("no ACK channel, ACK checks disabled")
+		}
+	}
+
+	switch *format {
+	case "vcd":
+		err = readVCD(f, names, header, feed)
+	case "csv":
+		err = readCSV(f, names, header, feed)
+	default:
+		err = fmt.Errorf("unknown capture format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "psxdecode:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d frames, %d violations\n", frames, faults)
+	if faults > 0 {
+		os.Exit(1)
+	}
+}
+
+// printFrame prints one decoded frame.
+func printFrame(f bus.Frame, violations []bus.Violation) {
+	fmt.Printf("%14v  %s\n", f.Time, bus.Describe(f))
+	fmt.Printf("%14s  CMD % X\n", "", f.Cmd)
+	fmt.Printf("%14s  DAT % X\n", "", f.Resp)
+	for _, v := range violations {
+		fmt.Printf("%14s  ! %v\n", "", v)
+	}
+}
+
+// missing returns an error naming a required channel that was not found.
+func missing(names [numLines]string, found [numLines]bool) error {
+	for i := lineATT; i < lineACK; i++ {
+		if !found[i] {
+			return fmt.Errorf("no channel named %q in capture", names[i])
+		}
+	}
+	return nil
+}
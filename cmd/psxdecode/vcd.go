@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpsx/bus"
+)
+
+// readVCD reads a Value Change Dump and calls feed once per timestamp
+// that changes a bus line. Only scalar signals are used.
+func readVCD(r io.Reader, names [numLines]string, header headerFunc, feed sampleFunc) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	sc.Split(bufio.ScanWords)
+
+	next := func() (string, bool) {
+		if !sc.Scan() {
+			return "", false
+		}
+		return sc.Text(), true
+	}
+	// skipSection skips to the $end of a header section.
+	skipSection := func() []string {
+		var words []string
+		for w, ok := next(); ok && w != "$end"; w, ok = next() {
+			words = append(words, w)
+		}
+		return words
+	}
+
+	// Timestamps are in nanoseconds unless the header says otherwise
+	unit := timescale{mul: 1, div: 1}
+	ids := make(map[string]int) // VCD identifier code to line
+	var found [numLines]bool
+
+	// Header
+	for {
+		w, ok := next()
+		if !ok {
+			return fmt.Errorf("vcd: no $enddefinitions")
+		}
+		switch w {
+		case "$timescale":
+			u, err := parseTimescale(strings.Join(skipSection(), ""))
+			if err != nil {
+				return err
+			}
+			unit = u
+			continue
+		case "$var":
+			// $var type size id name $end
+			words := skipSection()
+			if len(words) < 4 || words[1] != "1" {
+				continue
+			}
+			for i, n := range names {
+				if words[3] == n {
+					ids[words[2]] = i
+					found[i] = true
+				}
+			}
+			continue
+		case "$enddefinitions":
+			skipSection()
+		default:
+			if strings.HasPrefix(w, "$") {
+				skipSection()
+			}
+			continue
+		}
+		break
+	}
+	if err := missing(names, found); err != nil {
+		return err
+	}
+	header(found[lineACK])
+
+	// Lines idle high until the dump says otherwise
+	s := bus.Sample{ATT: true, CLK: true, CMD: true, DAT: true, ACK: true}
+	changed := false
+	for w, ok := next(); ok; w, ok = next() {
+		switch {
+		case w[0] == '#':
+			t, err := strconv.ParseUint(w[1:], 10, 64)
+			if err != nil {
+				return fmt.Errorf("vcd: bad timestamp %q", w)
+			}
+			if changed {
+				feed(s)
+				changed = false
+			}
+			s.Time = unit.duration(t)
+		case w[0] == 'b' || w[0] == 'B' || w[0] == 'r' || w[0] == 'R':
+			// Vector or real value: skip its identifier
+			next()
+		case w[0] == '$':
+			// $dumpvars, $end and friends wrap value changes
+		default:
+			line, ok := ids[w[1:]]
+			if !ok {
+				continue
+			}
+			// x and z read as high: DAT and ACK are pulled up
+			setLine(&s, line, w[0] != '0')
+			changed = true
+		}
+	}
+	if changed {
+		feed(s)
+	}
+	return sc.Err()
+}
+
+// timescale is the length of a VCD time unit, mul/div nanoseconds, so
+// units below the resolution of time.Duration stay exact.
+type timescale struct {
+	mul int64
+	div int64
+}
+
+// duration converts a timestamp in units to a time.Duration, rounding
+// down to the nanosecond.
+func (ts timescale) duration(t uint64) time.Duration {
+	return time.Duration(int64(t)/ts.div*ts.mul + int64(t)%ts.div*ts.mul/ts.div)
+}
+
+// parseTimescale parses a $timescale value such as "1ns" or "10 us".
+func parseTimescale(s string) (timescale, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return timescale{}, fmt.Errorf("vcd: bad timescale %q", s)
+	}
+	n, _ := strconv.ParseInt(s[:i], 10, 64)
+	if n == 0 {
+		return timescale{}, fmt.Errorf("vcd: bad timescale %q", s)
+	}
+	switch s[i:] {
+	case "s":
+		return timescale{mul: n * int64(time.Second), div: 1}, nil
+	case "ms":
+		return timescale{mul: n * int64(time.Millisecond), div: 1}, nil
+	case "us":
+		return timescale{mul: n * int64(time.Microsecond), div: 1}, nil
+	case "ns":
+		return timescale{mul: n, div: 1}, nil
+	case "ps":
+		return timescale{mul: n, div: 1000}, nil
+	case "fs":
+		return timescale{mul: n, div: 1000000}, nil
+	}
+	return timescale{}, fmt.Errorf("vcd: bad timescale %q", s)
+}
+
+// setLine sets one line of a sample.
+func setLine(s *bus.Sample, line int, high bool) {
+	switch line {
+	case lineATT:
+		s.ATT = high
+	case lineCLK:
+		s.CLK = high
+	case lineCMD:
+		s.CMD = high
+	case lineDAT:
+		s.DAT = high
+	case lineACK:
+		s.ACK = high
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gpsx/bus"
+)
+
+// testVCD holds two frames sending 0x01 to a silent bus, timed in
+// picoseconds. The second clocks its first bit 1us after ATT falls.
+const testVCD = `$timescale 1 ps $end
+$scope module top $end
+$var wire 1 ! ATT $end
+$var wire 1 " CLK $end
+$var wire 1 # CMD $end
+$var wire 1 $ DAT $end
+$upscope $end
+$enddefinitions $end
+$dumpvars 1! 1" 1# 1$ $end
+#10000000 0!
+#20000000 0" 1#
+#21000000 1"
+#22000000 0" 0#
+#23000000 1"
+#24000000 0"
+#25000000 1"
+#26000000 0"
+#27000000 1"
+#28000000 0"
+#29000000 1"
+#30000000 0"
+#31000000 1"
+#32000000 0"
+#33000000 1"
+#34000000 0"
+#35000000 1"
+#40000000 1! 1#
+#100000000 0!
+#101000000 0" 1#
+#102000000 1"
+#103000000 0" 0#
+#104000000 1"
+#105000000 0"
+#106000000 1"
+#107000000 0"
+#108000000 1"
+#109000000 0"
+#110000000 1"
+#111000000 0"
+#112000000 1"
+#113000000 0"
+#114000000 1"
+#115000000 0"
+#116000000 1"
+#120000000 1! 1#
+`
+
+var testNames = [numLines]string{"ATT", "CLK", "CMD", "DAT", "ACK"}
+
+func TestReadVCD(t *testing.T) {
+	d := bus.NewDecoder()
+	var frames []bus.Frame
+	var violations [][]bus.Violation
+	hasACK := true
+	header := func(ack bool) {
+		hasACK = ack
+		d.NoACK = !ack
+	}
+	feed := func(s bus.Sample) {
+		if d.Feed(s) {
+			f := d.Frame()
+			frames = append(frames, bus.Frame{
+				Time: f.Time,
+				Cmd:  append([]byte(nil), f.Cmd...),
+				Resp: append([]byte(nil), f.Resp...),
+			})
+			violations = append(violations, append([]bus.Violation(nil), d.Violations()...))
+		}
+	}
+
+	if err := readVCD(strings.NewReader(testVCD), testNames, header, feed); err != nil {
+		t.Fatal(err)
+	}
+	if hasACK {
+		t.Error("ACK reported present")
+	}
+	if len(frames) != 2 {
+		t.Fatalf("decoded %d frames, want 2", len(frames))
+	}
+
+	for i, at := range []time.Duration{10 * time.Microsecond, 100 * time.Microsecond} {
+		f := frames[i]
+		if f.Time != at || !bytes.Equal(f.Cmd, []byte{0x01}) || !bytes.Equal(f.Resp, []byte{0xFF}) {
+			t.Errorf("frame %d: %v CMD % X DAT % X, want %v CMD 01 DAT FF", i, f.Time, f.Cmd, f.Resp, at)
+		}
+	}
+	if len(violations[0]) != 0 {
+		t.Errorf("frame 0 violations %v, want none", violations[0])
+	}
+	if len(violations[1]) != 1 || violations[1][0].Time != 101*time.Microsecond {
+		t.Errorf("frame 1 violations %v, want the early first clock", violations[1])
+	}
+}
+
+func TestParseTimescale(t *testing.T) {
+	tests := []struct {
+		in   string
+		t    uint64
+		want time.Duration
+	}{
+		{"1s", 2, 2 * time.Second},
+		{"10ms", 3, 30 * time.Millisecond},
+		{"100us", 1, 100 * time.Microsecond},
+		{"1ns", 7, 7},
+		{"1ps", 2500, 2},
+		{"10ps", 250, 2},
+		{"100ps", 1234567, 123456},
+		{"1fs", 3000000, 3},
+	}
+	for _, tt := range tests {
+		ts, err := parseTimescale(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got := ts.duration(tt.t); got != tt.want {
+			t.Errorf("%s: %d units = %v, want %v", tt.in, tt.t, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "ns", "0ns", "1min", "1 as"} {
+		if _, err := parseTimescale(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
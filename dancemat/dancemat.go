@@ -0,0 +1,143 @@
+// Package dancemat maps PlayStation dance mats onto the gpsx Button table.
+//
+// Dance mats identify themselves as ordinary digital pads. The arrow panels
+// are wired to the D-pad and the corner panels to the face buttons, and
+// unlike a real D-pad a mat happily reports Left+Right or Up+Down at the
+// same time. This package reads each panel independently, so opposite
+// directions are never filtered out.
+package dancemat
+
+import (
+	"time"
+
+	"gpsx"
+)
+
+// Panel identifies a panel on the mat.
+type Panel uint8
+
+// Panel definitions
+const (
+	PanelLeft Panel = iota
+	PanelDown
+	PanelUp
+	PanelRight
+	PanelUpLeft
+	PanelUpRight
+	PanelDownLeft
+	PanelDownRight
+	PanelStart
+	PanelSelect
+
+	// NumPanels is the number of panels in a Profile.
+	NumPanels
+)
+
+// Profile maps each panel to the controller button it is wired to.
+type Profile [NumPanels]gpsx.Button
+
+// DefaultProfile is the layout of the common PlayStation dance mat:
+// arrows on the D-pad, Cross/Circle on the upper corners and
+// Triangle/Square on the lower corners.
+var DefaultProfile = Profile{
+	PanelLeft:      gpsx.ButtonLeft,
+	PanelDown:      gpsx.ButtonDown,
+	PanelUp:        gpsx.ButtonUp,
+	PanelRight:     gpsx.ButtonRight,
+	PanelUpLeft:    gpsx.ButtonCross,
+	PanelUpRight:   gpsx.ButtonCircle,
+	PanelDownLeft:  gpsx.ButtonTriangle,
+	PanelDownRight: gpsx.ButtonSquare,
+	PanelStart:     gpsx.ButtonStart,
+	PanelSelect:    gpsx.ButtonSelect,
+}
+
+// panelState holds the debounce state of a single panel.
+type panelState struct {
+	stable   bool      // debounced state
+	raw      bool      // state seen on the last poll
+	since    time.Time // time the raw state last changed
+	debounce time.Duration
+}
+
+// Mat reads a dance mat connected to a GPSX pad.
+type Mat struct {
+	psx     *gpsx.GPSX
+	pad     uint8
+	profile Profile
+
+	panels [NumPanels]panelState
+
+	// Bitmasks of panels that changed on the last Update
+	stepOn  uint16
+	stepOff uint16
+}
+
+// New creates a Mat reading the given pad with the given profile.
+// Debounce is disabled for all panels until SetDebounce is called.
+func New(psx *gpsx.GPSX, pad uint8, profile Profile) *Mat {
+	return &Mat{
+		psx:     psx,
+		pad:     pad,
+		profile: profile,
+	}
+}
+
+// SetDebounce sets how long a panel must stay in a new state before the
+// change is reported. Zero reports changes on the first poll that sees them.
+// Time is taken from the driver's poll times (see gpsx.GPSX.SetClock).
+func (m *Mat) SetDebounce(p Panel, d time.Duration) {
+	m.panels[p].debounce = d
+}
+
+// SetDebounceAll sets the same debounce time for every panel.
+func (m *Mat) SetDebounceAll(d time.Duration) {
+	for i := range m.panels {
+		m.panels[i].debounce = d
+	}
+}
+
+// Update polls the mat and updates the panel states.
+func (m *Mat) Update() {
+	m.psx.UpdateState(m.pad)
+	now := m.psx.PollTime(m.pad)
+
+	m.stepOn = 0
+	m.stepOff = 0
+	for i := range m.panels {
+		ps := &m.panels[i]
+
+		// Each panel is read on its own, so opposite directions
+		// can be down at the same time.
+		down := m.psx.IsDown(m.pad, m.profile[i])
+		if down != ps.raw {
+			ps.raw = down
+			ps.since = now
+		}
+
+		if ps.raw == ps.stable || now.Sub(ps.since) < ps.debounce {
+			continue
+		}
+		ps.stable = ps.raw
+		if ps.stable {
+			m.stepOn |= 1 << i
+		} else {
+			m.stepOff |= 1 << i
+		}
+	}
+}
+
+// IsDown returns true if the panel is currently stepped on.
+func (m *Mat) IsDown(p Panel) bool {
+	return m.panels[p].stable
+}
+
+// StepOn returns true if the panel was stepped on during the last Update.
+func (m *Mat) StepOn(p Panel) bool {
+	return m.stepOn&(1<<p) != 0
+}
+
+// StepOff returns true if the panel was released during the last Update.
+func (m *Mat) StepOff(p Panel) bool {
+	return m.stepOff&(1<<p) != 0
+}
@@ -0,0 +1,104 @@
+package dancemat_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/dancemat"
+	"gpsx/sim"
+)
+
+// testMat returns a mat on Pad1 of a sim, and a function that advances
+// the driver's clock by d and updates the mat.
+func testMat(profile dancemat.Profile) (*sim.Sim, *dancemat.Mat, func(d time.Duration)) {
+	s := sim.New(gpsx.PS2)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.PSX.SetClock(func() time.Time { return now })
+	m := dancemat.New(s.PSX, gpsx.Pad1, profile)
+	m.Update()
+	return s, m, func(d time.Duration) {
+		now = now.Add(d)
+		m.Update()
+	}
+}
+
+func TestPanels(t *testing.T) {
+	s, m, step := testMat(dancemat.DefaultProfile)
+
+	// Opposite arrows and a corner at once
+	s.Press(gpsx.Pad1, gpsx.ButtonLeft)
+	s.Press(gpsx.Pad1, gpsx.ButtonRight)
+	s.Press(gpsx.Pad1, gpsx.ButtonCircle)
+	step(10 * time.Millisecond)
+
+	down := map[dancemat.Panel]bool{
+		dancemat.PanelLeft:    true,
+		dancemat.PanelRight:   true,
+		dancemat.PanelUpRight: true,
+	}
+	for p := dancemat.Panel(0); p < dancemat.NumPanels; p++ {
+		if m.IsDown(p) != down[p] || m.StepOn(p) != down[p] {
+			t.Errorf("panel %d: down %v, step on %v, want %v", p, m.IsDown(p), m.StepOn(p), down[p])
+		}
+	}
+
+	s.Release(gpsx.Pad1, gpsx.ButtonLeft)
+	step(10 * time.Millisecond)
+	if m.IsDown(dancemat.PanelLeft) || !m.StepOff(dancemat.PanelLeft) {
+		t.Error("Left not released")
+	}
+	if !m.IsDown(dancemat.PanelRight) || m.StepOn(dancemat.PanelRight) || m.StepOff(dancemat.PanelRight) {
+		t.Error("Right changed while held")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	profile := dancemat.DefaultProfile
+	profile[dancemat.PanelStart] = gpsx.ButtonR1
+	s, m, step := testMat(profile)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonStart)
+	step(10 * time.Millisecond)
+	if m.IsDown(dancemat.PanelStart) {
+		t.Error("Start panel down on a button it is not wired to")
+	}
+	s.Press(gpsx.Pad1, gpsx.ButtonR1)
+	step(10 * time.Millisecond)
+	if !m.StepOn(dancemat.PanelStart) {
+		t.Error("Start panel not stepped on through R1")
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	s, m, step := testMat(dancemat.DefaultProfile)
+	m.SetDebounceAll(30 * time.Millisecond)
+	m.SetDebounce(dancemat.PanelDown, 0)
+
+	// A bounce shorter than the debounce is never reported
+	s.Press(gpsx.Pad1, gpsx.ButtonUp)
+	s.Press(gpsx.Pad1, gpsx.ButtonDown)
+	step(10 * time.Millisecond)
+	if !m.StepOn(dancemat.PanelDown) {
+		t.Error("Down not reported at once without debounce")
+	}
+	s.Release(gpsx.Pad1, gpsx.ButtonUp)
+	step(10 * time.Millisecond)
+	step(40 * time.Millisecond)
+	if m.IsDown(dancemat.PanelUp) {
+		t.Error("bounce reported")
+	}
+
+	// A step is reported once it has lasted the debounce time
+	s.Press(gpsx.Pad1, gpsx.ButtonUp)
+	var on []int
+	for i := 0; i < 6; i++ {
+		step(10 * time.Millisecond)
+		if m.StepOn(dancemat.PanelUp) {
+			on = append(on, i)
+		}
+	}
+	if len(on) != 1 || on[0] != 3 {
+		t.Errorf("Up stepped on at polls %v, want [3]", on)
+	}
+}
@@ -0,0 +1,210 @@
+// Package dengo reads Densha de GO! train controllers through gpsx.
+//
+// Two controllers are supported: the two-handle controller (Mascon) and
+// the Shinkansen controller (Shinkansen).
+//
+// The two-handle controller identifies itself as a digital pad. Each handle
+// closes four contacts wired to buttons: the power handle (mascon) uses
+// Left, Down, Right and Triangle, the brake handle uses R1, L1, R2 and L2.
+// Every handle position is a distinct contact pattern, and patterns seen
+// while a handle moves between two positions are reported as Unknown.
+package dengo
+
+import "gpsx"
+
+// Unknown is returned for a handle that is between two positions.
+const Unknown uint8 = 0xff
+
+// Handle contact definitions
+const (
+	handleContact1 uint8 = 0b0001
+	handleContact2 uint8 = 0b0010
+	handleContact3 uint8 = 0b0100
+	handleContact4 uint8 = 0b1000
+)
+
+// Notch position mapping
+const (
+	mapNotchOff uint8 = 0b0111
+	mapNotch1   uint8 = 0b1110
+	mapNotch2   uint8 = 0b0110
+	mapNotch3   uint8 = 0b1011
+	mapNotch4   uint8 = 0b0011
+	mapNotch5   uint8 = 0b1010
+)
+
+// Brake position mapping
+const (
+	mapBrakeOff  uint8 = 0b1101
+	mapBrake1    uint8 = 0b0111
+	mapBrake2    uint8 = 0b0101
+	mapBrake3    uint8 = 0b1110
+	mapBrake4    uint8 = 0b1100
+	mapBrake5    uint8 = 0b0110
+	mapBrake6    uint8 = 0b0100
+	mapBrake7    uint8 = 0b1011
+	mapBrake8    uint8 = 0b1001
+	mapBrakeEmer uint8 = 0b0000
+)
+
+// Handle ranges
+const (
+	NotchMax       uint8 = 5 // Highest power notch (P5)
+	BrakeMax       uint8 = 8 // Highest service brake (B8)
+	BrakeEmergency uint8 = 9 // Emergency brake (EB)
+
+	// HandleNeutral is the combined handle position with both handles
+	// released. Lower values are brake steps down to 0 (EB), higher
+	// values are power notches up to HandleNeutral+NotchMax.
+	HandleNeutral uint8 = 9
+)
+
+// State is the state of a two-handle controller.
+type State struct {
+	Notch  uint8 // Power notch 0-5, or Unknown
+	Brake  uint8 // Brake step 0-8, BrakeEmergency, or Unknown
+	Handle uint8 // Combined handle position 0-14, or Unknown
+
+	ButtonA      bool // Square
+	ButtonB      bool // Cross
+	ButtonC      bool // Circle
+	ButtonStart  bool
+	ButtonSelect bool
+}
+
+// DecodeNotch returns the power notch for a power handle contact pattern.
+func DecodeNotch(contacts uint8) uint8 {
+	switch contacts {
+	case mapNotchOff:
+		return 0
+	case mapNotch1:
+		return 1
+	case mapNotch2:
+		return 2
+	case mapNotch3:
+		return 3
+	case mapNotch4:
+		return 4
+	case mapNotch5:
+		return 5
+	default:
+		return Unknown
+	}
+}
+
+// DecodeBrake returns the brake step for a brake handle contact pattern.
+func DecodeBrake(contacts uint8) uint8 {
+	switch contacts {
+	case mapBrakeOff:
+		return 0
+	case mapBrake1:
+		return 1
+	case mapBrake2:
+		return 2
+	case mapBrake3:
+		return 3
+	case mapBrake4:
+		return 4
+	case mapBrake5:
+		return 5
+	case mapBrake6:
+		return 6
+	case mapBrake7:
+		return 7
+	case mapBrake8:
+		return 8
+	case mapBrakeEmer:
+		return BrakeEmergency
+	default:
+		return Unknown
+	}
+}
+
+// CombineHandle returns the combined handle position for a notch and
+// brake. The brake wins when both handles are applied.
+func CombineHandle(notch uint8, brake uint8) uint8 {
+	if notch == Unknown || brake == Unknown {
+		return Unknown
+	}
+	if brake != 0 {
+		return HandleNeutral - brake
+	}
+	return HandleNeutral + notch
+}
+
+// Buttons carrying the handle contacts
+var (
+	notchContacts = gpsx.ButtonsOf(gpsx.ButtonLeft, gpsx.ButtonDown, gpsx.ButtonRight, gpsx.ButtonTriangle)
+	brakeContacts = gpsx.ButtonsOf(gpsx.ButtonR1, gpsx.ButtonL1, gpsx.ButtonR2, gpsx.ButtonL2)
+)
+
+// Mascon polls a Densha de GO! two-handle controller.
+type Mascon struct {
+	psx      *gpsx.GPSX
+	pad      uint8
+	reverser Reverser
+}
+
+// New creates a Mascon reading the controller on the given pad.
+func New(psx *gpsx.GPSX, pad uint8) *Mascon {
+	return &Mascon{
+		psx: psx,
+		pad: pad,
+	}
+}
+
+// SetStable makes each handle report a new position only once its
+// contacts have read the same for polls consecutive polls, so the
+// combinations passed through between two notches are never seen and
+// Notch and Brake stay on the last real position instead of Unknown.
+// Polls of 0 turns the filter off.
+func (m *Mascon) SetStable(polls uint8) {
+	m.psx.SetStableGroup(m.pad, notchContacts, polls)
+	m.psx.SetStableGroup(m.pad, brakeContacts, polls)
+}
+
+// Update polls the controller and returns its state.
+// Each press of Select also advances the reverser.
+func (m *Mascon) Update() State {
+	m.psx.UpdateState(m.pad)
+
+	state := State{
+		Notch:        DecodeNotch(handleContacts(m.psx, m.pad, gpsx.ButtonLeft, gpsx.ButtonDown, gpsx.ButtonRight, gpsx.ButtonTriangle)),
+		Brake:        DecodeBrake(handleContacts(m.psx, m.pad, gpsx.ButtonR1, gpsx.ButtonL1, gpsx.ButtonR2, gpsx.ButtonL2)),
+		ButtonA:      m.psx.IsDown(m.pad, gpsx.ButtonSquare),
+		ButtonB:      m.psx.IsDown(m.pad, gpsx.ButtonCross),
+		ButtonC:      m.psx.IsDown(m.pad, gpsx.ButtonCircle),
+		ButtonStart:  m.psx.IsDown(m.pad, gpsx.ButtonStart),
+		ButtonSelect: m.psx.IsDown(m.pad, gpsx.ButtonSelect),
+	}
+	state.Handle = CombineHandle(state.Notch, state.Brake)
+
+	if m.psx.Pressed(m.pad, gpsx.ButtonSelect) {
+		m.reverser.Next()
+	}
+
+	return state
+}
+
+// Reverser returns the current reverser position.
+func (m *Mascon) Reverser() uint8 {
+	return m.reverser.Position()
+}
+
+// handleContacts packs four handle contacts into a contact pattern.
+func handleContacts(psx *gpsx.GPSX, pad uint8, c1, c2, c3, c4 gpsx.Button) uint8 {
+	var contacts uint8
+	if psx.IsDown(pad, c1) {
+		contacts |= handleContact1
+	}
+	if psx.IsDown(pad, c2) {
+		contacts |= handleContact2
+	}
+	if psx.IsDown(pad, c3) {
+		contacts |= handleContact3
+	}
+	if psx.IsDown(pad, c4) {
+		contacts |= handleContact4
+	}
+	return contacts
+}
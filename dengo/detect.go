@@ -0,0 +1,98 @@
+package dengo
+
+import "gpsx"
+
+// Variant identifies the kind of controller connected to a pad.
+type Variant uint8
+
+// Variant definitions
+const (
+	VariantUnknown    Variant = iota // No controller, or inconsistent polls
+	VariantPad                       // Ordinary digital pad (no config commands)
+	VariantTwoHandle                 // Two-handle controller (Mascon)
+	VariantShinkansen                // Shinkansen controller (Shinkansen)
+	VariantDualShock                 // DualShock or DualShock 2, in either mode
+)
+
+// detectPolls is the number of polls that must agree in Detect.
+const detectPolls = 4
+
+// String returns the name of the variant.
+func (v Variant) String() string {
+	switch v {
+	case VariantPad:
+		return "pad"
+	case VariantTwoHandle:
+		return "two-handle"
+	case VariantShinkansen:
+		return "shinkansen"
+	case VariantDualShock:
+		return "dualshock"
+	default:
+		return "unknown"
+	}
+}
+
+// Identify classifies the controller from the state read by the last
+// UpdateState.
+//
+// A train controller always reports a valid position for each of its
+// handles, which an ordinary pad at rest never does: the two-handle
+// contact patterns need several D-pad and shoulder buttons held at once,
+// and the Shinkansen levers and horn pedal sit on nominal values where
+// an analog stick rests near the centre.
+//
+// A pad in analog mode (ID 0x7x) that is not a Shinkansen controller is
+// reported as VariantDualShock. In digital mode (ID 0x4x) a DualShock
+// looks like any digital pad: only Detect, which asks for the config
+// reply, tells them apart.
+func Identify(psx *gpsx.GPSX, pad uint8) Variant {
+	switch {
+	case psx.IsDigital(pad):
+		notch := DecodeNotch(handleContacts(psx, pad, gpsx.ButtonLeft, gpsx.ButtonDown, gpsx.ButtonRight, gpsx.ButtonTriangle))
+		brake := DecodeBrake(handleContacts(psx, pad, gpsx.ButtonR1, gpsx.ButtonL1, gpsx.ButtonR2, gpsx.ButtonL2))
+		if notch != Unknown && brake != Unknown {
+			return VariantTwoHandle
+		}
+		return VariantPad
+
+	case psx.IsAnalog(pad):
+		brake := DecodeShinkansenBrake(psx.AnalogRightX(pad))
+		notch := DecodeShinkansenNotch(psx.AnalogRightY(pad))
+		horn := psx.AnalogLeftX(pad)
+		if notch != Unknown && brake != Unknown && (horn == 0x00 || horn == 0xFF) {
+			return VariantShinkansen
+		}
+		if psx.DeviceID(pad)&0xF0 == gpsx.DeviceAnalog&0xF0 {
+			return VariantDualShock
+		}
+		return VariantPad
+
+	default:
+		// Floating DAT line (no controller) or config mode
+		return VariantUnknown
+	}
+}
+
+// Detect polls the pad and returns the controller variant. The same
+// variant must be seen on several consecutive polls, otherwise
+// VariantUnknown is returned. A digital pad that answers config
+// commands is reported as VariantDualShock. Detect should be called while
+// the controller is not being operated, and before the pad's mode is set,
+// since forcing digital mode hides the Shinkansen controller's analog ID.
+func Detect(psx *gpsx.GPSX, pad uint8) Variant {
+	psx.UpdateState(pad)
+	variant := Identify(psx, pad)
+
+	for i := 1; i < detectPolls; i++ {
+		psx.UpdateState(pad)
+		if Identify(psx, pad) != variant {
+			return VariantUnknown
+		}
+	}
+
+	if variant == VariantPad && psx.IsDigital(pad) && psx.SupportsConfig(pad) {
+		return VariantDualShock
+	}
+	return variant
+}
@@ -0,0 +1,37 @@
+package dengo
+
+// Reverser positions
+const (
+	ReverserNeutral  uint8 = 0
+	ReverserForward  uint8 = 1
+	ReverserBackward uint8 = 2
+)
+
+// reverserSequence is the order the reverser steps through:
+// neutral -> forward -> neutral -> backward.
+var reverserSequence = [4]uint8{ReverserNeutral, ReverserForward, ReverserNeutral, ReverserBackward}
+
+// Reverser emulates a reverser lever driven by a single button.
+// The controllers have no reverser, so the examples step it with Select.
+type Reverser struct {
+	step uint8
+}
+
+// Next advances the reverser and returns its new position.
+func (r *Reverser) Next() uint8 {
+	r.step++
+	if r.step >= uint8(len(reverserSequence)) {
+		r.step = 0
+	}
+	return r.Position()
+}
+
+// Position returns the current reverser position.
+func (r *Reverser) Position() uint8 {
+	return reverserSequence[r.step]
+}
+
+// Reset returns the reverser to neutral.
+func (r *Reverser) Reset() {
+	r.step = 0
+}
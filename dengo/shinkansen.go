@@ -0,0 +1,128 @@
+package dengo
+
+import "gpsx"
+
+// The Shinkansen controller reports in analog mode. Its levers are
+// absolute positions encoded as analog bytes instead of contact patterns:
+//
+//	Byte             | Accessor     | Content
+//	-----------------|--------------|------------------------------
+//	5                | AnalogRightX | Brake lever
+//	6                | AnalogRightY | Power lever
+//	7                | AnalogLeftX  | Horn pedal (0x00 up, 0xFF down)
+//
+// Each lever position has a nominal value. The lever reports 0xFF
+// while it moves between two positions.
+
+// Shinkansen handle ranges
+const (
+	ShinkansenNotchMax       uint8 = 13 // Highest power notch (P13)
+	ShinkansenBrakeMax       uint8 = 7  // Highest service brake (B7)
+	ShinkansenBrakeEmergency uint8 = 8  // Emergency brake (EB)
+)
+
+// shinkansenTolerance is how far a lever byte may be from its nominal value.
+const shinkansenTolerance = 2
+
+// Nominal brake lever values: released, B1-B7, EB
+var shinkansenBrakeMap = [ShinkansenBrakeEmergency + 1]uint8{
+	0x1C, 0x38, 0x54, 0x70, 0x8B, 0xA7, 0xC3, 0xDF, 0xFB,
+}
+
+// Nominal power lever values: N, P1-P13
+var shinkansenNotchMap = [ShinkansenNotchMax + 1]uint8{
+	0x12, 0x24, 0x36, 0x48, 0x5A, 0x6C, 0x7E,
+	0x90, 0xA2, 0xB4, 0xC6, 0xD7, 0xE9, 0xFB,
+}
+
+// shinkansenHornThreshold is the pedal value above which the horn sounds.
+const shinkansenHornThreshold uint8 = 0x80
+
+// ShinkansenState is the state of a Shinkansen controller.
+type ShinkansenState struct {
+	Notch     uint8 // Power notch 0-13, or Unknown
+	Brake     uint8 // Brake step 0-7, ShinkansenBrakeEmergency, or Unknown
+	Emergency bool  // Brake lever in the EB position
+
+	Horn bool // Horn pedal
+	Door bool // Triangle (D button, door close)
+
+	ButtonA      bool // Square
+	ButtonB      bool // Cross
+	ButtonC      bool // Circle
+	ButtonStart  bool
+	ButtonSelect bool
+}
+
+// DecodeShinkansenNotch returns the power notch for a power lever value.
+func DecodeShinkansenNotch(value uint8) uint8 {
+	return decodeLever(shinkansenNotchMap[:], value)
+}
+
+// DecodeShinkansenBrake returns the brake step for a brake lever value.
+func DecodeShinkansenBrake(value uint8) uint8 {
+	return decodeLever(shinkansenBrakeMap[:], value)
+}
+
+// decodeLever returns the index of the nominal value close to value.
+func decodeLever(nominal []uint8, value uint8) uint8 {
+	for i, n := range nominal {
+		d := int(value) - int(n)
+		if d >= -shinkansenTolerance && d <= shinkansenTolerance {
+			return uint8(i)
+		}
+	}
+	return Unknown
+}
+
+// Shinkansen polls a Densha de GO! Shinkansen controller.
+type Shinkansen struct {
+	psx      *gpsx.GPSX
+	pad      uint8
+	reverser Reverser
+}
+
+// NewShinkansen creates a Shinkansen reading the controller on the given pad.
+// The pad must be in analog mode.
+func NewShinkansen(psx *gpsx.GPSX, pad uint8) *Shinkansen {
+	return &Shinkansen{
+		psx: psx,
+		pad: pad,
+	}
+}
+
+// Update polls the controller and returns its state.
+// Each press of Select also advances the reverser.
+func (s *Shinkansen) Update() ShinkansenState {
+	s.psx.UpdateState(s.pad)
+
+	state := ShinkansenState{
+		Notch:        Unknown,
+		Brake:        Unknown,
+		Door:         s.psx.IsDown(s.pad, gpsx.ButtonTriangle),
+		ButtonA:      s.psx.IsDown(s.pad, gpsx.ButtonSquare),
+		ButtonB:      s.psx.IsDown(s.pad, gpsx.ButtonCross),
+		ButtonC:      s.psx.IsDown(s.pad, gpsx.ButtonCircle),
+		ButtonStart:  s.psx.IsDown(s.pad, gpsx.ButtonStart),
+		ButtonSelect: s.psx.IsDown(s.pad, gpsx.ButtonSelect),
+	}
+
+	// The levers are only reported in analog mode
+	if s.psx.IsAnalog(s.pad) {
+		state.Brake = DecodeShinkansenBrake(s.psx.AnalogRightX(s.pad))
+		state.Notch = DecodeShinkansenNotch(s.psx.AnalogRightY(s.pad))
+		state.Emergency = state.Brake == ShinkansenBrakeEmergency
+		state.Horn = s.psx.AnalogLeftX(s.pad) >= shinkansenHornThreshold
+	}
+
+	if s.psx.Pressed(s.pad, gpsx.ButtonSelect) {
+		s.reverser.Next()
+	}
+
+	return state
+}
+
+// Reverser returns the current reverser position.
+func (s *Shinkansen) Reverser() uint8 {
+	return s.reverser.Position()
+}
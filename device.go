@@ -0,0 +1,150 @@
+//go:build tinygo
+
+package gpsx
+
+import (
+	"machine"
+	"time"
+
+	"gpsx/bus"
+)
+
+// Device-side ACK timing
+const (
+	deviceAckDelay = 5 * time.Microsecond // Last clock edge to ACK
+	deviceAckPulse = 3 * time.Microsecond // ACK low time
+)
+
+// DevicePinConfig holds the pin configuration for the device side of the
+// bus, used when the board stands in for a controller or a memory card.
+// DAT and ACK are driven as open drain outputs, so the board can share
+// the bus with other devices.
+type DevicePinConfig struct {
+	ATT machine.Pin // Attention input
+	CLK machine.Pin // Clock input
+	CMD machine.Pin // Command input
+	DAT machine.Pin // Data output (open drain)
+	ACK machine.Pin // Acknowledge output (open drain)
+}
+
+// Device answers a console on the bus with a bus.Responder.
+type Device struct {
+	pins DevicePinConfig
+
+	ackDelay time.Duration
+	ackPulse time.Duration
+
+	datLow bool
+}
+
+// NewDevice creates a Device on the given pins.
+func NewDevice(pins DevicePinConfig) *Device {
+	d := &Device{
+		pins:     pins,
+		ackDelay: deviceAckDelay,
+		ackPulse: deviceAckPulse,
+	}
+
+	d.pins.ATT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	d.pins.CLK.Configure(machine.PinConfig{Mode: machine.PinInput})
+	d.pins.CMD.Configure(machine.PinConfig{Mode: machine.PinInput})
+	d.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	d.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
+
+	return d
+}
+
+// Serve answers every frame with r. It never returns.
+func (d *Device) Serve(r bus.Responder) {
+	for {
+		d.ServeFrame(r, 0)
+	}
+}
+
+// ServeFrame waits for the console to select the slot and answers one
+// frame with r. It returns false if no frame started within timeout;
+// a zero timeout waits forever.
+func (d *Device) ServeFrame(r bus.Responder, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for d.pins.ATT.Get() {
+		if timeout > 0 && time.Now().After(deadline) {
+			return false
+		}
+	}
+
+	r.Select()
+
+	// Nothing is driven during the address byte
+	out := bus.HiZ
+	for {
+		in, ok := d.transferByte(out)
+		if !ok {
+			break
+		}
+
+		next, ack := r.Exchange(in)
+		if !ack {
+			break
+		}
+		out = next
+		d.ack()
+	}
+
+	// Stay off the bus until the console releases attention
+	d.setDAT(true)
+	for !d.pins.ATT.Get() {
+	}
+
+	r.Deselect()
+	return true
+}
+
+// transferByte shifts out one byte while shifting in the console's byte.
+// Data changes on the falling clock edge and is sampled on the rising
+// edge, LSB first. It returns false if attention is released mid-byte.
+func (d *Device) transferByte(out byte) (byte, bool) {
+	var in byte
+	for i := 0; i < 8; i++ {
+		for d.pins.CLK.Get() {
+			if d.pins.ATT.Get() {
+				return 0, false
+			}
+		}
+		d.setDAT(out&0x01 != 0)
+		out >>= 1
+
+		for !d.pins.CLK.Get() {
+			if d.pins.ATT.Get() {
+				return 0, false
+			}
+		}
+		in >>= 1
+		if d.pins.CMD.Get() {
+			in |= 0x80
+		}
+	}
+	return in, true
+}
+
+// ack pulses ACK low to ask the console for the next byte.
+func (d *Device) ack() {
+	time.Sleep(d.ackDelay)
+	d.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	d.pins.ACK.Low()
+	time.Sleep(d.ackPulse)
+	d.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
+}
+
+// setDAT drives DAT low, or releases it to be pulled high.
+func (d *Device) setDAT(high bool) {
+	if high == !d.datLow {
+		return
+	}
+	if high {
+		d.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	} else {
+		d.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinOutput})
+		d.pins.DAT.Low()
+	}
+	d.datLow = !high
+}
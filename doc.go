@@ -7,9 +7,32 @@
 //
 //   - Support for both PS1 and PS2 controllers
 //   - Digital and analog mode support
+//   - PS1 Analog Joystick (SCPH-1110) axes and stick buttons
 //   - Dual controller support (PAD1 and PAD2)
 //   - Motor/vibration control
+//   - PS1 memory card frame read/write
+//   - PS1 memory card filesystem, .mcr/.mcs import and export (package gpsx/ps1mc)
+//   - PS2 memory card page read/write/erase with ECC and filesystem reader (package gpsx/ps2mc)
+//   - Device side of the bus: DualShock 2 and PS1 memory card emulation
+//     (Device, package gpsx/dualshock, ps1mc.Emulator)
+//   - Controller passthrough with remapping, turbo and rumble relay (package gpsx/passthrough)
+//   - Simulated pads on an in-process bus, so the driver runs under plain
+//     `go test` without hardware (NewWithTransport, package gpsx/sim)
+//   - Bus transcript recording and replay for reproducing reports (GPSX.Record, bus.Replay)
+//   - Logic analyzer capture decoding with timing checks (bus.Decoder, cmd/psxdecode)
+//   - Passive bus sniffer for watching a console configure its pads (Sniffer)
 //   - Edge detection for button press/release events
+//   - State snapshots with a Buttons bitmask (GPSX.Poll, GPSX.State)
+//   - Timestamped button event queue with overflow reporting (GPSX.NextEvent)
+//   - Allocation-free press, release, hold and mode-change callbacks (GPSX.OnPress etc.)
+//   - Per-button debounce and stable multi-contact groups (GPSX.SetDebounce, GPSX.SetStableGroup)
+//   - Hold duration, long-press, auto-repeat and double-tap detection (GPSX.HeldFor etc.)
+//   - Motion, charge and button sequence recognition (package gpsx/motion)
+//   - Centred stick values with deadzones and response curves (GPSX.Stick)
+//   - Stick calibration with a serializable record (GPSX.Calibrator, Calibration)
+//   - Stick drift measurement and automatic recentring (GPSX.SetDriftCompensation)
+//   - Dance mat panels with per-panel debounce (package gpsx/dancemat)
+//   - Densha de GO! train controllers (package gpsx/dengo)
 //
 // # Hardware Connection
 //
@@ -0,0 +1,142 @@
+package gpsx
+
+import (
+	"math"
+	"time"
+)
+
+// DriftConfig sets how the resting position of the sticks is tracked.
+type DriftConfig struct {
+	// Settle is how long a stick must stay still before its position is
+	// taken as the rest position. Zero turns tracking off.
+	Settle time.Duration
+
+	// Tolerance is how far, in raw units, each axis may wander while
+	// still counting as still.
+	Tolerance uint8
+
+	// Limit is the largest drift accepted, in raw units from the centre.
+	// A stick held still further out is being held by someone. Values
+	// above 127 act as 127.
+	Limit uint8
+
+	// Correct subtracts the drift from the stick values. Without it the
+	// drift is only measured, for Drift to report.
+	Correct bool
+}
+
+// DefaultDriftConfig suits a worn DualShock: two seconds of stillness
+// within 2 units, up to 40 units off centre, corrected.
+var DefaultDriftConfig = DriftConfig{
+	Settle:    2 * time.Second,
+	Tolerance: 2,
+	Limit:     40,
+	Correct:   true,
+}
+
+// stillness follows one stick while it stays still.
+type stillness struct {
+	start time.Time
+	min   [2]uint8
+	max   [2]uint8
+	sum   [2]uint32
+	n     uint32
+}
+
+// restart begins a still period at the given axis values.
+func (s *stillness) restart(now time.Time, x, y uint8) {
+	s.start = now
+	s.min = [2]uint8{x, y}
+	s.max = [2]uint8{x, y}
+	s.sum = [2]uint32{}
+	s.n = 0
+}
+
+// driftTracker estimates the drift of one pad's sticks.
+type driftTracker struct {
+	cfg    DriftConfig
+	still  [2]stillness // Indexed by Stick
+	offset [4]int8      // Indexed by Axis
+}
+
+// SetDriftCompensation sets drift tracking for pad and forgets the drift
+// measured so far. Each stick is watched from UpdateState; once it has
+// stayed still for cfg.Settle, its average position becomes the new
+// rest position and the difference from the centre is the drift. The
+// centre is the calibrated one if the pad is calibrated, 0x80 otherwise.
+func (g *GPSX) SetDriftCompensation(pad uint8, cfg DriftConfig) {
+	g.drift[pad] = driftTracker{cfg: cfg}
+}
+
+// Drift returns how far the rest position of an axis of pad has moved
+// from the centre, in raw units, as last measured.
+func (g *GPSX) Drift(pad uint8, axis Axis) int {
+	return int(g.drift[pad].offset[axis&3])
+}
+
+// ResetDrift forgets the drift measured on pad.
+func (g *GPSX) ResetDrift(pad uint8) {
+	g.drift[pad] = driftTracker{cfg: g.drift[pad].cfg}
+}
+
+// track adds the raw stick values of a poll made at now.
+func (d *driftTracker) track(raw *[4]uint8, centre *[4]uint8, now time.Time) {
+	if d.cfg.Settle <= 0 {
+		return
+	}
+
+	for stick := range d.still {
+		s := &d.still[stick]
+		// Right stick is axes 0-1, left stick axes 2-3
+		x, y := raw[2*stick], raw[2*stick+1]
+		if s.start.IsZero() {
+			s.restart(now, x, y)
+		}
+
+		for i, v := range [2]uint8{x, y} {
+			s.min[i] = min(s.min[i], v)
+			s.max[i] = max(s.max[i], v)
+		}
+		if s.max[0]-s.min[0] > d.cfg.Tolerance || s.max[1]-s.min[1] > d.cfg.Tolerance {
+			s.restart(now, x, y)
+		}
+		s.sum[0] += uint32(x)
+		s.sum[1] += uint32(y)
+		s.n++
+
+		if now.Sub(s.start) < d.cfg.Settle {
+			continue
+		}
+
+		limit := min(int(d.cfg.Limit), math.MaxInt8)
+		var offset [2]int
+		for i := range offset {
+			rest := int((s.sum[i] + s.n/2) / s.n)
+			offset[i] = rest - int(centre[2*stick+i])
+		}
+		if abs(offset[0]) <= limit && abs(offset[1]) <= limit {
+			d.offset[2*stick] = int8(offset[0])
+			d.offset[2*stick+1] = int8(offset[1])
+		}
+		s.restart(now, x, y)
+	}
+}
+
+// correct removes the drift from raw stick values.
+func (d *driftTracker) correct(sticks []uint8) {
+	if !d.cfg.Correct {
+		return
+	}
+	for i := range sticks {
+		v := int(sticks[i]) - int(d.offset[i])
+		sticks[i] = uint8(max(0, min(0xFF, v)))
+	}
+}
+
+// abs returns the absolute value of v.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
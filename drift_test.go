@@ -0,0 +1,118 @@
+package gpsx_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/sim"
+)
+
+// driftSim returns a sim with Pad1 in analog mode, drift tracking set to
+// cfg and the right stick held at x, y.
+func driftSim(cfg gpsx.DriftConfig, x, y uint8) (*sim.Sim, *fakeClock) {
+	s := sim.New(gpsx.PS2)
+	c := newClock(s)
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+	s.PSX.SetDriftCompensation(gpsx.Pad1, cfg)
+	s.Sticks(gpsx.Pad1, x, y, 0x80, 0x80)
+	return s, c
+}
+
+// hold polls pad every 100ms for d.
+func (c *fakeClock) hold(s *sim.Sim, pad uint8, d time.Duration) {
+	for t := time.Duration(0); t < d; t += 100 * time.Millisecond {
+		c.step(s, pad, 100*time.Millisecond)
+	}
+}
+
+func TestDriftSettle(t *testing.T) {
+	cfg := gpsx.DriftConfig{Settle: time.Second, Tolerance: 2, Limit: 40, Correct: true}
+	s, c := driftSim(cfg, 0x88, 0x78)
+
+	c.hold(s, gpsx.Pad1, 900*time.Millisecond)
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != 0 {
+		t.Fatalf("drift %d before Settle", d)
+	}
+	if x := s.PSX.AnalogRightX(gpsx.Pad1); x != 0x88 {
+		t.Errorf("right X 0x%02X before Settle, want 0x88", x)
+	}
+
+	c.hold(s, gpsx.Pad1, 200*time.Millisecond)
+	if dx, dy := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX), s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightY); dx != 8 || dy != -8 {
+		t.Errorf("drift %d,%d, want 8,-8", dx, dy)
+	}
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisLeftX); d != 0 {
+		t.Errorf("left stick drift %d, want 0", d)
+	}
+	if x, y := s.PSX.AnalogRightX(gpsx.Pad1), s.PSX.AnalogRightY(gpsx.Pad1); x != 0x80 || y != 0x80 {
+		t.Errorf("corrected stick 0x%02X,0x%02X, want centred", x, y)
+	}
+
+	s.PSX.ResetDrift(gpsx.Pad1)
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != 0 {
+		t.Errorf("drift %d after ResetDrift", d)
+	}
+}
+
+func TestDriftMovementRestarts(t *testing.T) {
+	cfg := gpsx.DriftConfig{Settle: time.Second, Tolerance: 2, Limit: 40, Correct: true}
+	s, c := driftSim(cfg, 0x88, 0x80)
+
+	c.hold(s, gpsx.Pad1, 600*time.Millisecond)
+	// Within Tolerance keeps the window going
+	s.Sticks(gpsx.Pad1, 0x8A, 0x80, 0x80, 0x80)
+	c.hold(s, gpsx.Pad1, 300*time.Millisecond)
+	// Beyond it starts a new one
+	s.Sticks(gpsx.Pad1, 0x90, 0x80, 0x80, 0x80)
+	c.hold(s, gpsx.Pad1, 600*time.Millisecond)
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != 0 {
+		t.Fatalf("drift %d after moving, want the window restarted", d)
+	}
+
+	c.hold(s, gpsx.Pad1, 500*time.Millisecond)
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != 0x10 {
+		t.Errorf("drift %d, want 16", d)
+	}
+}
+
+func TestDriftLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit uint8
+		cal   bool
+		x     uint8
+		want  int
+	}{
+		{"within", 40, false, 0x80 + 40, 40},
+		{"beyond", 40, false, 0x80 + 41, 0},
+		{"limit above int8", 0xFF, false, 0xFF, 127},
+		// Calibrated centre 0x20 puts 0xF0 at +208, beyond any drift
+		{"beyond int8", 0xFF, true, 0xF0, 0},
+	}
+	for _, tt := range tests {
+		cfg := gpsx.DriftConfig{Settle: time.Second, Tolerance: 2, Limit: tt.limit, Correct: true}
+		s, c := driftSim(cfg, tt.x, 0x80)
+		if tt.cal {
+			a := gpsx.AxisCalibration{Min: 0x00, Centre: 0x20, Max: 0xFF}
+			s.PSX.SetCalibration(gpsx.Pad1, gpsx.Calibration{Axes: [4]gpsx.AxisCalibration{a, a, a, a}})
+		}
+		c.hold(s, gpsx.Pad1, 1100*time.Millisecond)
+		if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != tt.want {
+			t.Errorf("%s: drift %d, want %d", tt.name, d, tt.want)
+		}
+	}
+}
+
+func TestDriftMeasureOnly(t *testing.T) {
+	cfg := gpsx.DriftConfig{Settle: time.Second, Tolerance: 2, Limit: 40}
+	s, c := driftSim(cfg, 0x88, 0x80)
+
+	c.hold(s, gpsx.Pad1, 1100*time.Millisecond)
+	if d := s.PSX.Drift(gpsx.Pad1, gpsx.AxisRightX); d != 8 {
+		t.Errorf("drift %d, want 8", d)
+	}
+	if x := s.PSX.AnalogRightX(gpsx.Pad1); x != 0x88 {
+		t.Errorf("right X 0x%02X, want uncorrected 0x88", x)
+	}
+}
@@ -0,0 +1,329 @@
+// Package dualshock implements the device side of a DualShock 2: the
+// state machine that answers a console's polls and config commands.
+//
+// A Controller is a bus.Responder, so it can be served to a real console
+// through gpsx.Device or driven in-process by a bus.Host. The application
+// sets buttons and sticks between frames and reads back the mode and
+// motor levels the game asked for.
+package dualshock
+
+import (
+	"gpsx"
+	"gpsx/bus"
+)
+
+// Device IDs, the low nibble is the payload length in halfwords
+const (
+	idDigital  byte = 0x41
+	idAnalog   byte = 0x73
+	idPressure byte = 0x79
+	idConfig   byte = 0xF3
+)
+
+// Commands
+const (
+	cmdPressureMask byte = 0x41 // Query pressure mask
+	cmdPoll         byte = 0x42
+	cmdConfig       byte = 0x43 // Enter/exit config mode
+	cmdSetMode      byte = 0x44
+	cmdStatus       byte = 0x45
+	cmdConst46      byte = 0x46
+	cmdConst47      byte = 0x47
+	cmdConst4C      byte = 0x4C
+	cmdMotorMap     byte = 0x4D
+	cmdSetPressure  byte = 0x4F
+)
+
+// Motor map values
+const (
+	motorSmall byte = 0x00 // Command byte drives the small motor (on/off)
+	motorLarge byte = 0x01 // Command byte drives the large motor (speed)
+	motorNone  byte = 0xFF
+)
+
+// Longest poll response: header, 0x5A and 18 payload bytes
+const (
+	maxFrame = 21
+	maxReply = maxFrame - 2
+)
+
+// Order of the pressure bytes in a poll response
+var pressureOrder = [12]gpsx.Button{
+	gpsx.ButtonRight, gpsx.ButtonLeft, gpsx.ButtonUp, gpsx.ButtonDown,
+	gpsx.ButtonTriangle, gpsx.ButtonCircle, gpsx.ButtonCross, gpsx.ButtonSquare,
+	gpsx.ButtonL1, gpsx.ButtonR1, gpsx.ButtonL2, gpsx.ButtonR2,
+}
+
+// Controller answers polls and config commands like a DualShock 2.
+type Controller struct {
+	// Poll response as seen by the host: bytes 3-4 buttons (active LOW),
+	// 5-8 sticks, 9-20 pressures
+	data [maxFrame]byte
+
+	analog   bool
+	locked   bool
+	config   bool
+	pressure bool
+	motorMap [6]byte
+
+	smallMotor bool
+	largeMotor uint8
+
+	// Frame in progress
+	pos    int
+	cmd    byte
+	silent bool
+	params [6]byte
+	reply  [maxReply]byte
+	length int // Bytes in the frame
+}
+
+// New creates a Controller in digital mode with all buttons released and
+// the sticks centred.
+func New() *Controller {
+	c := &Controller{}
+	c.data[3] = 0xFF
+	c.data[4] = 0xFF
+	c.SetSticks(0x80, 0x80, 0x80, 0x80)
+	for i := range c.motorMap {
+		c.motorMap[i] = motorNone
+	}
+	return c
+}
+
+// SetButton presses or releases a button. Buttons with a pressure
+// sensor report full pressure while pressed.
+func (c *Controller) SetButton(btn gpsx.Button, down bool) {
+	btn.SetDown(c.data[:], down)
+	for i, b := range pressureOrder {
+		if b == btn {
+			c.data[9+i] = 0x00
+			if down {
+				c.data[9+i] = 0xFF
+			}
+		}
+	}
+}
+
+// SetPressure sets the pressure reported for a button (0-255).
+// It has no effect on buttons without a pressure sensor.
+func (c *Controller) SetPressure(btn gpsx.Button, value uint8) {
+	for i, b := range pressureOrder {
+		if b == btn {
+			c.data[9+i] = value
+		}
+	}
+}
+
+// SetSticks sets the analog stick positions (0-255, 0x80 is centre).
+func (c *Controller) SetSticks(rx, ry, lx, ly uint8) {
+	c.data[5] = rx
+	c.data[6] = ry
+	c.data[7] = lx
+	c.data[8] = ly
+}
+
+// SetAnalog switches between digital and analog mode, like the ANALOG
+// button. It is ignored while the game has locked the mode.
+func (c *Controller) SetAnalog(analog bool) {
+	if !c.locked {
+		c.setAnalog(analog)
+	}
+}
+
+// Analog returns true if the controller is in analog mode.
+func (c *Controller) Analog() bool {
+	return c.analog
+}
+
+// Locked returns true if the game locked the mode.
+func (c *Controller) Locked() bool {
+	return c.locked
+}
+
+// Config returns true if the controller is in config mode.
+func (c *Controller) Config() bool {
+	return c.config
+}
+
+// Motors returns the motor levels requested by the last poll.
+func (c *Controller) Motors() (small bool, large uint8) {
+	return c.smallMotor, c.largeMotor
+}
+
+// ID returns the device ID the controller currently reports.
+func (c *Controller) ID() byte {
+	switch {
+	case c.config:
+		return idConfig
+	case c.analog && c.pressure:
+		return idPressure
+	case c.analog:
+		return idAnalog
+	default:
+		return idDigital
+	}
+}
+
+// Select starts a new frame.
+func (c *Controller) Select() {
+	c.pos = 0
+	c.silent = false
+}
+
+// Exchange receives a byte from the console and prepares the next reply.
+func (c *Controller) Exchange(cmd byte) (byte, bool) {
+	if c.silent {
+		return bus.HiZ, false
+	}
+
+	p := c.pos
+	c.pos++
+
+	switch {
+	case p == 0:
+		if cmd != bus.AddressController {
+			return c.stop()
+		}
+		return c.ID(), true
+	case p == 1:
+		if !c.begin(cmd) {
+			return c.stop()
+		}
+		return c.reply[0], true
+	}
+
+	// Bytes 3-8 carry the command parameters
+	if i := p - 3; i >= 0 && i < len(c.params) {
+		c.params[i] = cmd
+		c.parameter(i, cmd)
+	}
+
+	// No ACK after the last byte
+	if p+1 >= c.length {
+		c.end()
+		return c.stop()
+	}
+	return c.reply[p-1], true
+}
+
+// Deselect ends the frame.
+func (c *Controller) Deselect() {
+	c.silent = true
+}
+
+// begin prepares the reply to a command. It returns false for commands
+// the controller does not answer in its current mode.
+func (c *Controller) begin(cmd byte) bool {
+	c.cmd = cmd
+	for i := range c.params {
+		c.params[i] = 0x00
+	}
+
+	reply := c.reply[:]
+	reply[0] = 0x5A
+	for i := 1; i < len(reply); i++ {
+		reply[i] = 0x00
+	}
+
+	payload := 6
+	if cmd == cmdPoll || (cmd == cmdConfig && !c.config) {
+		// Poll data, sized by the mode
+		payload = int(c.ID()&0x0F) * 2
+		copy(reply[1:], c.data[3:3+payload])
+		c.length = 3 + payload
+		return true
+	}
+	if !c.config {
+		return false
+	}
+
+	switch cmd {
+	case cmdConfig, cmdSetMode, cmdSetPressure:
+		if cmd == cmdSetPressure {
+			reply[6] = 0x5A
+		}
+	case cmdPressureMask:
+		if c.analog {
+			copy(reply[1:], []byte{0xFF, 0xFF, 0x03, 0x00, 0x00, 0x5A})
+		}
+	case cmdStatus:
+		copy(reply[1:], []byte{0x03, 0x02, 0x00, 0x02, 0x01, 0x00})
+		if c.analog {
+			reply[3] = 0x01
+		}
+	case cmdConst46:
+		copy(reply[1:], []byte{0x00, 0x00, 0x01, 0x02, 0x00, 0x0A})
+	case cmdConst47:
+		copy(reply[1:], []byte{0x00, 0x00, 0x02, 0x00, 0x01, 0x00})
+	case cmdConst4C:
+		copy(reply[1:], []byte{0x00, 0x00, 0x00, 0x04, 0x00, 0x00})
+	case cmdMotorMap:
+		copy(reply[1:], c.motorMap[:])
+	default:
+		return false
+	}
+	c.length = 3 + payload
+	return true
+}
+
+// parameter handles parameter byte i as it arrives, for replies that
+// depend on it.
+func (c *Controller) parameter(i int, value byte) {
+	if i != 0 {
+		return
+	}
+	switch {
+	case c.cmd == cmdConst46 && value == 0x01:
+		copy(c.reply[3:], []byte{0x01, 0x01, 0x01, 0x14})
+	case c.cmd == cmdConst4C && value == 0x01:
+		c.reply[4] = 0x07
+	}
+}
+
+// end applies the command once all its bytes have been received.
+func (c *Controller) end() {
+	switch c.cmd {
+	case cmdPoll:
+		c.smallMotor = false
+		c.largeMotor = 0
+		for i, m := range c.motorMap {
+			// Until the game maps the motors they stay off
+			switch m {
+			case motorSmall:
+				c.smallMotor = c.params[i]&0x01 != 0
+			case motorLarge:
+				c.largeMotor = c.params[i]
+			}
+		}
+	case cmdConfig:
+		c.config = c.params[0] == 0x01
+	case cmdSetMode:
+		if c.config {
+			c.setAnalog(c.params[0] == 0x01)
+			c.locked = c.params[1] == 0x03
+		}
+	case cmdMotorMap:
+		if c.config {
+			copy(c.motorMap[:], c.params[:])
+		}
+	case cmdSetPressure:
+		if c.config {
+			c.pressure = c.params[0]|c.params[1]|c.params[2] != 0
+		}
+	}
+}
+
+// setAnalog changes the mode. Pressure reporting is reset with it.
+func (c *Controller) setAnalog(analog bool) {
+	c.analog = analog
+	if !analog {
+		c.pressure = false
+	}
+}
+
+// stop ends the reply for this frame.
+func (c *Controller) stop() (byte, bool) {
+	c.silent = true
+	return bus.HiZ, false
+}
@@ -0,0 +1,124 @@
+package gpsx
+
+import "time"
+
+// eventQueueSize is the number of events kept until the application
+// drains them.
+const eventQueueSize = 32
+
+// EventKind tells what happened to a button.
+type EventKind uint8
+
+// Event kinds
+const (
+	EventPress EventKind = iota
+	EventRelease
+)
+
+// String returns "press" or "release".
+func (k EventKind) String() string {
+	if k == EventPress {
+		return "press"
+	}
+	return "release"
+}
+
+// Event is a button press or release seen by UpdateState.
+type Event struct {
+	Time   time.Time // When the poll that saw it was made
+	Pad    uint8
+	Kind   EventKind
+	Button Button
+}
+
+// eventQueue is a fixed-size ring of events.
+type eventQueue struct {
+	events  [eventQueueSize]Event
+	head    int
+	count   int
+	dropped int
+	ch      chan<- Event
+}
+
+// push adds an event, or counts it as dropped if there is no room.
+func (q *eventQueue) push(e Event) {
+	if q.ch != nil {
+		select {
+		case q.ch <- e:
+		default:
+			q.dropped++
+		}
+		return
+	}
+	if q.count == len(q.events) {
+		q.dropped++
+		return
+	}
+	q.events[(q.head+q.count)%len(q.events)] = e
+	q.count++
+}
+
+// NextEvent removes and returns the oldest queued button event. It
+// returns false when the queue is empty. Events are queued by every
+// UpdateState, so taps shorter than the application's loop are not lost
+// as long as the queue is drained before it fills.
+func (g *GPSX) NextEvent() (Event, bool) {
+	q := &g.events
+	if q.count == 0 {
+		return Event{}, false
+	}
+	e := q.events[q.head]
+	q.head = (q.head + 1) % len(q.events)
+	q.count--
+	return e, true
+}
+
+// DroppedEvents returns the number of events lost because the queue (or
+// channel) was full since the last call, and resets the count.
+func (g *GPSX) DroppedEvents() int {
+	n := g.events.dropped
+	g.events.dropped = 0
+	return n
+}
+
+// SendEvents delivers events to ch instead of the queue. Sends never
+// block: if ch is full the event is dropped and counted. SendEvents(nil)
+// goes back to queueing.
+func (g *GPSX) SendEvents(ch chan<- Event) {
+	g.events.ch = ch
+}
+
+// edges returns the buttons that changed on the last poll of pad and the
+// buttons now held. It returns false after the first poll, which has
+// nothing to compare with.
+func (g *GPSX) edges(pad uint8) (changed, down Buttons, ok bool) {
+	prev := &g.keyState[pad][statePrevious]
+	if prev[1] == 0 {
+		return 0, 0, false
+	}
+	changed = Buttons(prev[3]) | Buttons(prev[4])<<8
+	down = ParseButtons(g.keyState[pad][stateCurrent][:])
+	return changed, down, true
+}
+
+// queueEvents records the buttons that changed on the last poll of pad.
+func (g *GPSX) queueEvents(pad uint8, now time.Time) {
+	changed, down, ok := g.edges(pad)
+	if !ok || changed == 0 {
+		return
+	}
+
+	// Releases first, so a press of another button in the same poll
+	// comes after the release it replaced
+	g.pushEvents(now, pad, EventRelease, changed&^down)
+	g.pushEvents(now, pad, EventPress, changed&down)
+}
+
+// pushEvents queues an event of kind for every button in b.
+func (g *GPSX) pushEvents(now time.Time, pad uint8, kind EventKind, b Buttons) {
+	for i, e := range buttonTable {
+		if b&(1<<i) != 0 {
+			g.events.push(Event{Time: now, Pad: pad, Kind: kind, Button: e.btn})
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package gpsx_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/sim"
+)
+
+// eventSim returns a sim with Pad1 polled once, so that the next polls
+// produce events.
+func eventSim() (*sim.Sim, *fakeClock) {
+	s := sim.New(gpsx.PS2)
+	c := newClock(s)
+	s.Poll(gpsx.Pad1)
+	return s, c
+}
+
+// toggle makes n edges of Cross on Pad1, one poll every 10ms, starting
+// with a press.
+func toggle(s *sim.Sim, c *fakeClock, n int) {
+	for i := 0; i < n; i++ {
+		if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
+			s.Release(gpsx.Pad1, gpsx.ButtonCross)
+		} else {
+			s.Press(gpsx.Pad1, gpsx.ButtonCross)
+		}
+		c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	}
+}
+
+// drain returns every queued event.
+func drain(s *sim.Sim) []gpsx.Event {
+	var events []gpsx.Event
+	for {
+		e, ok := s.PSX.NextEvent()
+		if !ok {
+			return events
+		}
+		events = append(events, e)
+	}
+}
+
+// checkEdges checks that events are the edges made by toggle from the
+// edge numbered first, the first of them polled at start.
+func checkEdges(t *testing.T, events []gpsx.Event, first int, start time.Time) {
+	t.Helper()
+	for i, e := range events {
+		n := first + i
+		want := gpsx.Event{
+			Time:   start.Add(time.Duration(i) * 10 * time.Millisecond),
+			Pad:    gpsx.Pad1,
+			Kind:   gpsx.EventKind(n % 2),
+			Button: gpsx.ButtonCross,
+		}
+		if e != want {
+			t.Errorf("event %d: %v %v at %v, want %v at %v", n, e.Kind, e.Button, e.Time, want.Kind, want.Time)
+		}
+	}
+}
+
+func TestEventOrder(t *testing.T) {
+	s, c := eventSim()
+	s.Press(gpsx.Pad1, gpsx.ButtonCircle)
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.step(s, gpsx.Pad1, time.Millisecond)
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	s.Press(gpsx.Pad1, gpsx.ButtonStart)
+	c.step(s, gpsx.Pad1, time.Millisecond)
+
+	want := []struct {
+		kind gpsx.EventKind
+		btn  gpsx.Button
+	}{
+		{gpsx.EventPress, gpsx.ButtonCircle},
+		{gpsx.EventPress, gpsx.ButtonCross},
+		// Releases come before presses of the same poll
+		{gpsx.EventRelease, gpsx.ButtonCross},
+		{gpsx.EventPress, gpsx.ButtonStart},
+	}
+	events := drain(s)
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		if e := events[i]; e.Kind != w.kind || e.Button != w.btn {
+			t.Errorf("event %d: %v %v, want %v %v", i, e.Kind, e.Button, w.kind, w.btn)
+		}
+	}
+}
+
+func TestEventQueueWrap(t *testing.T) {
+	s, c := eventSim()
+	start := c.now().Add(10 * time.Millisecond)
+
+	toggle(s, c, 20)
+	for i := 0; i < 10; i++ {
+		s.PSX.NextEvent()
+	}
+	// 30 queued, running past the end of the ring
+	toggle(s, c, 20)
+	if n := s.PSX.DroppedEvents(); n != 0 {
+		t.Fatalf("%d events dropped with room left", n)
+	}
+	events := drain(s)
+	if len(events) != 30 {
+		t.Fatalf("%d events, want 30", len(events))
+	}
+	checkEdges(t, events, 10, start.Add(10*10*time.Millisecond))
+
+	// Drained, the queue takes a full 32 again
+	toggle(s, c, 35)
+	if n := s.PSX.DroppedEvents(); n != 3 {
+		t.Errorf("%d events dropped, want 3", n)
+	}
+	if n := len(drain(s)); n != 32 {
+		t.Errorf("%d events after overflow, want 32", n)
+	}
+}
+
+func TestEventQueueOverflow(t *testing.T) {
+	s, c := eventSim()
+	start := c.now().Add(10 * time.Millisecond)
+
+	toggle(s, c, 45)
+	if n := s.PSX.DroppedEvents(); n != 13 {
+		t.Errorf("%d events dropped, want 13", n)
+	}
+	if n := s.PSX.DroppedEvents(); n != 0 {
+		t.Errorf("DroppedEvents did not reset: %d", n)
+	}
+
+	// The oldest events are kept, the newest dropped
+	events := drain(s)
+	if len(events) != 32 {
+		t.Fatalf("%d events, want 32", len(events))
+	}
+	checkEdges(t, events, 0, start)
+	if _, ok := s.PSX.NextEvent(); ok {
+		t.Error("event left after draining")
+	}
+}
+
+func TestSendEvents(t *testing.T) {
+	s, c := eventSim()
+	start := c.now().Add(10 * time.Millisecond)
+
+	ch := make(chan gpsx.Event, 2)
+	s.PSX.SendEvents(ch)
+	toggle(s, c, 5)
+	if n := s.PSX.DroppedEvents(); n != 3 {
+		t.Errorf("%d events dropped on a full channel, want 3", n)
+	}
+	if _, ok := s.PSX.NextEvent(); ok {
+		t.Error("event queued while sending to a channel")
+	}
+	close(ch)
+	var events []gpsx.Event
+	for e := range ch {
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("%d events sent, want 2", len(events))
+	}
+	checkEdges(t, events, 0, start)
+
+	// Back to the queue
+	s.PSX.SendEvents(nil)
+	toggle(s, c, 1)
+	if n := len(drain(s)); n != 1 {
+		t.Errorf("%d events queued after SendEvents(nil), want 1", n)
+	}
+}
@@ -0,0 +1,64 @@
+// Example program turning push buttons into a DualShock 2 for a console.
+// Target: Raspberry Pi Pico
+//
+// Each button connects a GPIO to GND. The board answers the console's
+// polls and config commands, so games can switch it to analog mode and
+// drive rumble, reported here on the LED.
+package main
+
+import (
+	"machine"
+
+	"gpsx"
+	"gpsx/dualshock"
+)
+
+// inputs maps GPIO pins to controller buttons.
+var inputs = []struct {
+	pin machine.Pin
+	btn gpsx.Button
+}{
+	{machine.GP10, gpsx.ButtonUp},
+	{machine.GP11, gpsx.ButtonDown},
+	{machine.GP12, gpsx.ButtonLeft},
+	{machine.GP13, gpsx.ButtonRight},
+	{machine.GP14, gpsx.ButtonSquare},
+	{machine.GP15, gpsx.ButtonCross},
+	{machine.GP16, gpsx.ButtonCircle},
+	{machine.GP17, gpsx.ButtonTriangle},
+	{machine.GP18, gpsx.ButtonL1},
+	{machine.GP19, gpsx.ButtonR1},
+	{machine.GP20, gpsx.ButtonStart},
+	{machine.GP21, gpsx.ButtonSelect},
+}
+
+func main() {
+	// Pins connected to the console's controller port
+	pins := gpsx.DevicePinConfig{
+		ATT: machine.GP5,
+		CLK: machine.GP4,
+		CMD: machine.GP3,
+		DAT: machine.GP2,
+		ACK: machine.GP7,
+	}
+
+	for _, in := range inputs {
+		in.pin.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	}
+	machine.LED.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	dev := gpsx.NewDevice(pins)
+	pad := dualshock.New()
+
+	for {
+		// Update the inputs between frames
+		for _, in := range inputs {
+			pad.SetButton(in.btn, !in.pin.Get())
+		}
+
+		dev.ServeFrame(pad, 0)
+
+		small, large := pad.Motors()
+		machine.LED.Set(small || large > 0)
+	}
+}
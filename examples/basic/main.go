@@ -28,9 +28,19 @@ func main() {
 	// Enable motors
 	psx.MotorEnable(gpsx.Pad1, gpsx.Motor1Enable, gpsx.Motor2Enable)
 
+	// Ignore small stick movements around the centre
+	sticks := gpsx.StickConfig{Mode: gpsx.DeadzoneScaledRadial, Deadzone: 28}
+	psx.SetStickConfig(gpsx.Pad1, gpsx.StickLeft, sticks)
+	psx.SetStickConfig(gpsx.Pad1, gpsx.StickRight, sticks)
+
 	// Initial state poll
 	psx.UpdateState(gpsx.Pad1)
 
+	// Hold Select at power-on to calibrate the sticks
+	if psx.IsDown(gpsx.Pad1, gpsx.ButtonSelect) {
+		calibrate(psx)
+	}
+
 	for {
 		// Poll controller state
 		psx.UpdateState(gpsx.Pad1)
@@ -69,22 +79,20 @@ func main() {
 
 		// Read analog sticks (only valid in analog mode)
 		if psx.IsAnalog(gpsx.Pad1) {
-			lx := psx.AnalogLeftX(gpsx.Pad1)
-			ly := psx.AnalogLeftY(gpsx.Pad1)
-			rx := psx.AnalogRightX(gpsx.Pad1)
-			ry := psx.AnalogRightY(gpsx.Pad1)
+			lx, ly := psx.Stick(gpsx.Pad1, gpsx.StickLeft)
+			rx, ry := psx.Stick(gpsx.Pad1, gpsx.StickRight)
 
-			// Print if stick is moved significantly from center (128)
-			if lx < 100 || lx > 156 || ly < 100 || ly > 156 {
+			// Print if stick is moved out of the deadzone
+			if lx != 0 || ly != 0 {
 				println("Left stick:", lx, ly)
 			}
-			if rx < 100 || rx > 156 || ry < 100 || ry > 156 {
+			if rx != 0 || ry != 0 {
 				println("Right stick:", rx, ry)
 			}
 
 			// Use left stick X to control motor 2 speed with Triangle button
 			if psx.IsDown(gpsx.Pad1, gpsx.ButtonTriangle) {
-				psx.Motor(gpsx.Pad1, gpsx.Motor1Off, lx)
+				psx.Motor(gpsx.Pad1, gpsx.Motor1Off, psx.AnalogLeftX(gpsx.Pad1))
 			}
 		}
 
@@ -92,3 +100,32 @@ func main() {
 		time.Sleep(20 * time.Millisecond)
 	}
 }
+
+// calibrate measures the stick travel and applies it. Firmware would
+// normally save the record from MarshalBinary to flash and restore it
+// with UnmarshalBinary and SetCalibration at start-up.
+func calibrate(psx *gpsx.GPSX) {
+	c := psx.Calibrator(gpsx.Pad1)
+
+	println("Calibrating: leave the sticks alone")
+	for i := 0; i < 50; i++ {
+		psx.UpdateState(gpsx.Pad1)
+		c.Rest()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	println("Roll both sticks around their full travel")
+	for i := 0; i < 250; i++ {
+		psx.UpdateState(gpsx.Pad1)
+		c.Sweep()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cal, err := c.Result()
+	if err != nil {
+		println("Calibration failed:", err.Error())
+		return
+	}
+	psx.SetCalibration(gpsx.Pad1, cal)
+	println("Calibration done")
+}
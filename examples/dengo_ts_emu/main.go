@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"gpsx"
+	"gpsx/dengo"
 )
 
 // ====================================================================
@@ -34,53 +35,6 @@ var tsCmdButtonB = [2]string{"TSY00", "TSY99"}
 var tsCmdButtonC = [2]string{"TSZ00", "TSZ99"}
 var tsCmdButtonStart = [2]string{"TSK00", "TSK99"}
 var tsCmdButtonReverser = [3]string{"TSG50", "TSG99", "TSG00"} // 中立, 前, 後
-var reverserState = [4]uint8{0, 1, 0, 2}                       // セレクトボタンで状態遷移 中立→前進→中立→後退
-
-// MasconState はマスコンの状態を表す構造体
-type MasconState struct {
-	notch        uint8
-	brake        uint8
-	handle       uint8
-	buttonA      bool
-	buttonB      bool
-	buttonC      bool
-	buttonStart  bool
-	buttonSelect bool
-}
-
-// ハンドル接点の定義
-const (
-	handleContact1 uint8 = 0b0001
-	handleContact2 uint8 = 0b0010
-	handleContact3 uint8 = 0b0100
-	handleContact4 uint8 = 0b1000
-)
-
-// ノッチポジションのマッピング
-const (
-	mapNotchOff uint8 = 0b0111
-	mapNotch1   uint8 = 0b1110
-	mapNotch2   uint8 = 0b0110
-	mapNotch3   uint8 = 0b1011
-	mapNotch4   uint8 = 0b0011
-	mapNotch5   uint8 = 0b1010
-)
-
-// ブレーキポジションのマッピング
-const (
-	mapBrakeOff  uint8 = 0b1101
-	mapBrake1    uint8 = 0b0111
-	mapBrake2    uint8 = 0b0101
-	mapBrake3    uint8 = 0b1110
-	mapBrake4    uint8 = 0b1100
-	mapBrake5    uint8 = 0b0110
-	mapBrake6    uint8 = 0b0100
-	mapBrake7    uint8 = 0b1011
-	mapBrake8    uint8 = 0b1001
-	mapBrakeEmer uint8 = 0b0000
-)
-
-var psx *gpsx.GPSX
 
 func main() {
 	// UARTの初期化 (TSマスコンは19200bps、デバッグ用に115200bps)
@@ -97,79 +51,81 @@ func main() {
 	}
 
 	// PSコントローラライブラリの初期化
-	psx = gpsx.New(gpsx.PS2, pins)
+	psx := gpsx.New(gpsx.PS2, pins)
+
+	// 接続されたコントローラの判別（ハンドルは操作しないこと）
+	for {
+		variant := dengo.Detect(psx, gpsx.Pad1)
+		if variant == dengo.VariantTwoHandle {
+			break
+		}
+		println("unsupported controller:", variant.String())
+		time.Sleep(1 * time.Second)
+	}
+
+	// 判別してからモードを設定する（先にデジタルに固定すると新幹線コントローラを判別できない）
 	psx.Mode(gpsx.Pad1, gpsx.ModeDigital, gpsx.ModeLock)
 	psx.MotorEnable(gpsx.Pad1, gpsx.Motor1Disable, gpsx.Motor2Disable)
 
+	// マスコンの初期化
+	mascon := dengo.New(psx, gpsx.Pad1)
+
 	// レバーサの初期状態を送信
 	println("TSG50")
 
 	// 状態保持用変数
-	var reverserPosition uint8 = 0
-	lastMasconState := MasconState{
-		notch:        0xff,
-		brake:        0xff,
-		handle:       0xff,
-		buttonA:      false,
-		buttonB:      false,
-		buttonC:      false,
-		buttonStart:  false,
-		buttonSelect: false,
+	lastMasconState := dengo.State{
+		Notch:  dengo.Unknown,
+		Brake:  dengo.Unknown,
+		Handle: dengo.Unknown,
 	}
+	lastReverser := mascon.Reverser()
 
 	// メインループ
 	for {
-		masconState := getMasconState()
+		masconState := mascon.Update()
 
 		// コントローラの状態に応じてTSマスコンのコマンドを投げる
-		if lastMasconState.handle != masconState.handle && masconState.handle != 0xff {
+		if lastMasconState.Handle != masconState.Handle && masconState.Handle != dengo.Unknown {
 			// 前回から変わったとき、かつ正常に取得できたときだけ
-			println(tsCmdHandle[masconState.handle])
+			println(tsCmdHandle[masconState.Handle])
 			println("---")
-			lastMasconState.handle = masconState.handle
+			lastMasconState.Handle = masconState.Handle
 		}
 
-		if lastMasconState.buttonA != masconState.buttonA {
-			idx := boolToInt(masconState.buttonA)
+		if lastMasconState.ButtonA != masconState.ButtonA {
+			idx := boolToInt(masconState.ButtonA)
 			println(tsCmdButtonA[idx])
 			println("---")
-			lastMasconState.buttonA = masconState.buttonA
+			lastMasconState.ButtonA = masconState.ButtonA
 		}
 
-		if lastMasconState.buttonB != masconState.buttonB {
-			idx := boolToInt(masconState.buttonB)
+		if lastMasconState.ButtonB != masconState.ButtonB {
+			idx := boolToInt(masconState.ButtonB)
 			println(tsCmdButtonB[idx])
 			println("---")
-			lastMasconState.buttonB = masconState.buttonB
+			lastMasconState.ButtonB = masconState.ButtonB
 		}
 
-		if lastMasconState.buttonC != masconState.buttonC {
-			idx := boolToInt(masconState.buttonC)
+		if lastMasconState.ButtonC != masconState.ButtonC {
+			idx := boolToInt(masconState.ButtonC)
 			println(tsCmdButtonC[idx])
 			println("---")
-			lastMasconState.buttonC = masconState.buttonC
+			lastMasconState.ButtonC = masconState.ButtonC
 		}
 
-		if lastMasconState.buttonStart != masconState.buttonStart {
-			idx := boolToInt(masconState.buttonStart)
+		if lastMasconState.ButtonStart != masconState.ButtonStart {
+			idx := boolToInt(masconState.ButtonStart)
 			println(tsCmdButtonStart[idx])
 			println("---")
-			lastMasconState.buttonStart = masconState.buttonStart
+			lastMasconState.ButtonStart = masconState.ButtonStart
 		}
 
-		if lastMasconState.buttonSelect != masconState.buttonSelect {
-			// セレクトは押されるたびにレバーサの状態を変える
-			if masconState.buttonSelect {
-				// 押されたとき
-				reverserPosition++
-				if reverserPosition > 3 {
-					reverserPosition = 0
-				}
-				println(tsCmdButtonReverser[reverserState[reverserPosition]])
-				println("---")
-			}
-			// 離されたときは処理なし
-			lastMasconState.buttonSelect = masconState.buttonSelect
+		// セレクトが押されるたびにレバーサの状態が変わる
+		if reverser := mascon.Reverser(); reverser != lastReverser {
+			println(tsCmdButtonReverser[reverser])
+			println("---")
+			lastReverser = reverser
 		}
 
 		// ポーリング間隔が65ms以上開くとワンハンドルタイプではリセットされる。(2~60がOK範囲)
@@ -184,132 +140,3 @@ func boolToInt(b bool) int {
 	}
 	return 0
 }
-
-// getNotchState はノッチポジションから状態を取得する
-func getNotchState(notchPosition uint8) uint8 {
-	switch notchPosition {
-	case mapNotchOff:
-		return 0
-	case mapNotch1:
-		return 1
-	case mapNotch2:
-		return 2
-	case mapNotch3:
-		return 3
-	case mapNotch4:
-		return 4
-	case mapNotch5:
-		return 5
-	default:
-		return 0xff // 中途半端な状態は取得しない
-	}
-}
-
-// getBrakeState はブレーキポジションから状態を取得する
-func getBrakeState(brakePosition uint8) uint8 {
-	switch brakePosition {
-	case mapBrakeOff:
-		return 0
-	case mapBrake1:
-		return 1
-	case mapBrake2:
-		return 2
-	case mapBrake3:
-		return 3
-	case mapBrake4:
-		return 4
-	case mapBrake5:
-		return 5
-	case mapBrake6:
-		return 6
-	case mapBrake7:
-		return 7
-	case mapBrake8:
-		return 8
-	case mapBrakeEmer:
-		return 9
-	default:
-		return 0xff // 中途半端な状態は取得しない
-	}
-}
-
-// getMasconState はマスコンの状態を取得する
-func getMasconState() MasconState {
-	// 戻り値を初期化
-	currentState := MasconState{
-		notch:        0xff,
-		brake:        0xff,
-		handle:       0xff,
-		buttonA:      false,
-		buttonB:      false,
-		buttonC:      false,
-		buttonStart:  false,
-		buttonSelect: false,
-	}
-
-	// PSコントローラの状態更新
-	psx.UpdateState(gpsx.Pad1)
-
-	// ノッチ状態の取得
-	var notchPosition uint8 = 0
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonLeft) {
-		notchPosition |= handleContact1
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonDown) {
-		notchPosition |= handleContact2
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonRight) {
-		notchPosition |= handleContact3
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonTriangle) {
-		notchPosition |= handleContact4
-	}
-	currentState.notch = getNotchState(notchPosition)
-
-	// ブレーキ状態の取得
-	var brakePosition uint8 = 0
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonR1) {
-		brakePosition |= handleContact1
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonL1) {
-		brakePosition |= handleContact2
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonR2) {
-		brakePosition |= handleContact3
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonL2) {
-		brakePosition |= handleContact4
-	}
-	currentState.brake = getBrakeState(brakePosition)
-
-	// ハンドルポジションは正常に取得できた場合のみ更新
-	if currentState.brake != 0xff && currentState.notch != 0xff {
-		// ブレーキが入っていない場合のみノッチを返す。ブレーキ解除が9なのでオフセットする。
-		if currentState.brake != 0 {
-			currentState.handle = 9 - currentState.brake
-		} else {
-			currentState.handle = currentState.notch + 9
-		}
-	} else {
-		currentState.handle = 0xff
-	}
-
-	// ボタン状態の取得
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonSquare) {
-		currentState.buttonA = true
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
-		currentState.buttonB = true
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonCircle) {
-		currentState.buttonC = true
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonStart) {
-		currentState.buttonStart = true
-	}
-	if psx.IsDown(gpsx.Pad1, gpsx.ButtonSelect) {
-		currentState.buttonSelect = true
-	}
-
-	return currentState
-}
@@ -0,0 +1,7 @@
+module example/memcard
+
+go 1.25.1
+
+replace gpsx => /home/satoken/work/tinygo/GPSX/gpsx
+
+require gpsx v0.0.0-00010101000000-000000000000
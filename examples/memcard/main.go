@@ -0,0 +1,131 @@
+// Example program backing up a PS1 memory card over USB serial.
+// Target: Raspberry Pi Pico
+//
+// Serial commands (one character):
+//
+//	l: list the saves on the card
+//	d: dump the whole card as a raw .mcr image (131072 bytes)
+//	r: restore a raw .mcr image sent right after the command
+//
+// Errors are reported on UART0 (GP0) rather than USB serial, so they never
+// end up in the middle of a dump. A dump stops at the first unreadable
+// frame.
+package main
+
+import (
+	"machine"
+	"strconv"
+	"time"
+
+	"gpsx"
+	"gpsx/ps1mc"
+)
+
+func main() {
+	// Configure pins for Raspberry Pi Pico
+	pins := gpsx.PinConfig{
+		DAT: machine.GP2, // Data input (requires external 1k pull-up)
+		CMD: machine.GP3, // Command output
+		CLK: machine.GP4, // Clock output
+		AT1: machine.GP5, // Attention for slot 1
+		ACK: machine.GP7, // ACK input (memory cards acknowledge late)
+	}
+
+	psx := gpsx.New(gpsx.PS1, pins)
+	card := psx.MemoryCard(gpsx.Pad1)
+
+	serial := machine.Serial
+	diag := machine.UART0
+	diag.Configure(machine.UARTConfig{BaudRate: 115200})
+
+	for {
+		cmd, err := serial.ReadByte()
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		if !card.Present() {
+			report(diag, "no memory card", 0, nil)
+			continue
+		}
+
+		switch cmd {
+		case 'l':
+			list(card)
+		case 'd':
+			if f, err := dump(card, serial); err != nil {
+				report(diag, "read error at frame", f, err)
+			}
+		case 'r':
+			if f, err := restore(card, serial); err != nil {
+				report(diag, "write error at frame", f, err)
+			} else {
+				println("restore done")
+			}
+		}
+	}
+}
+
+// list prints the saves on the card. Only the directory and the title
+// frames are read.
+func list(card *gpsx.MemoryCard) {
+	img, err := ps1mc.LoadDirectory(card)
+	if err != nil {
+		println("read error:", err.Error())
+		return
+	}
+
+	saves, err := img.Saves()
+	if err != nil {
+		println("directory error:", err.Error())
+		return
+	}
+	for _, s := range saves {
+		println(s.Name, len(s.Blocks), img.TitleASCII(s))
+	}
+	println("free blocks:", img.FreeBlocks())
+}
+
+// report prints an error on the diagnostics UART.
+func report(diag *machine.UART, msg string, frame uint16, err error) {
+	diag.Write([]byte(msg))
+	if err != nil {
+		diag.Write([]byte(" " + strconv.Itoa(int(frame)) + ": " + err.Error()))
+	}
+	diag.Write([]byte("\r\n"))
+}
+
+// dump sends the card frame by frame, so the whole image never has to
+// fit in RAM. It stops at the first frame that cannot be read and
+// returns its number.
+func dump(card *gpsx.MemoryCard, serial machine.Serialer) (uint16, error) {
+	var frame [ps1mc.FrameSize]byte
+	for f := uint16(0); f < ps1mc.Frames; f++ {
+		if err := card.ReadFrame(f, frame[:]); err != nil {
+			return f, err
+		}
+		serial.Write(frame[:])
+	}
+	return 0, nil
+}
+
+// restore writes an image received over serial frame by frame.
+func restore(card *gpsx.MemoryCard, serial machine.Serialer) (uint16, error) {
+	var frame [ps1mc.FrameSize]byte
+	for f := uint16(0); f < ps1mc.Frames; f++ {
+		for i := range frame {
+			for {
+				b, err := serial.ReadByte()
+				if err == nil {
+					frame[i] = b
+					break
+				}
+			}
+		}
+		if err := card.WriteFrame(f, frame[:]); err != nil {
+			return f, err
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,91 @@
+// Example program making the board act as a PS1 memory card.
+// Target: Raspberry Pi Pico
+//
+// The card image is kept in the flash data area. It is loaded into RAM at
+// start up, and blocks written by the console are saved back to flash
+// once no memory card frame has been seen for a second. Controller polls
+// share ATT with the card slot, so the bus itself is never idle for long.
+package main
+
+import (
+	"machine"
+	"time"
+
+	"gpsx"
+	"gpsx/ps1mc"
+)
+
+// flashCard is a card image in RAM backed by the flash data area.
+type flashCard struct {
+	img     *ps1mc.Image
+	dirty   [ps1mc.CardSize / 4096]bool
+	pending bool
+	last    time.Time // last card access by the console
+}
+
+// ReadFrame reads a frame from the RAM image.
+func (c *flashCard) ReadFrame(frame uint16, data []byte) error {
+	c.last = time.Now()
+	return c.img.ReadFrame(frame, data)
+}
+
+// WriteFrame writes a frame to the RAM image and marks its flash sector.
+func (c *flashCard) WriteFrame(frame uint16, data []byte) error {
+	c.last = time.Now()
+	if err := c.img.WriteFrame(frame, data); err != nil {
+		return err
+	}
+	c.dirty[int(frame)*ps1mc.FrameSize/4096] = true
+	c.pending = true
+	return nil
+}
+
+// flush writes the modified sectors back to flash.
+func (c *flashCard) flush() {
+	size := machine.Flash.EraseBlockSize()
+	for i, dirty := range c.dirty {
+		if !dirty {
+			continue
+		}
+		off := int64(i) * 4096
+		machine.Flash.EraseBlocks(off/size, 4096/size)
+		machine.Flash.WriteAt(c.img[off:off+4096], off)
+		c.dirty[i] = false
+	}
+	c.pending = false
+}
+
+func main() {
+	// Pins connected to the console's memory card slot
+	pins := gpsx.DevicePinConfig{
+		ATT: machine.GP5,
+		CLK: machine.GP4,
+		CMD: machine.GP3,
+		DAT: machine.GP2,
+		ACK: machine.GP7,
+	}
+
+	card := &flashCard{img: new(ps1mc.Image)}
+	machine.Flash.ReadAt(card.img[:], 0)
+	if !card.img.Formatted() {
+		println("formatting card image")
+		card.img.Format()
+		for i := range card.dirty {
+			card.dirty[i] = true
+		}
+		card.flush()
+	}
+
+	dev := gpsx.NewDevice(pins)
+	emu := ps1mc.NewEmulator(card)
+
+	for {
+		dev.ServeFrame(emu, 100*time.Millisecond)
+
+		// The console has stopped using the card: safe to spend time
+		// erasing flash, it only misses controller polls meant for the pad
+		if card.pending && time.Since(card.last) > time.Second {
+			card.flush()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Example program remapping a controller on an original console.
+// Target: Raspberry Pi Pico
+//
+// The real controller is wired to the board as in the basic example, and
+// a second set of pins goes to the console's controller port. Cross and
+// Circle are swapped, Square has turbo, and the left stick is made more
+// sensitive.
+package main
+
+import (
+	"machine"
+
+	"gpsx"
+	"gpsx/passthrough"
+)
+
+func main() {
+	// Pins connected to the real controller
+	pins := gpsx.PinConfig{
+		DAT: machine.GP2, // Data input (requires external 1k pull-up)
+		CMD: machine.GP3, // Command output
+		CLK: machine.GP4, // Clock output
+		AT1: machine.GP5, // Attention for PAD1
+	}
+
+	// Pins connected to the console
+	console := gpsx.DevicePinConfig{
+		ATT: machine.GP10,
+		CLK: machine.GP11,
+		CMD: machine.GP12,
+		DAT: machine.GP13,
+		ACK: machine.GP14,
+	}
+
+	psx := gpsx.New(gpsx.PS2, pins)
+	dev := gpsx.NewDevice(console)
+
+	p := passthrough.New(psx, gpsx.Pad1, dev)
+	p.Remap(gpsx.ButtonCross, gpsx.ButtonCircle)
+	p.Remap(gpsx.ButtonCircle, gpsx.ButtonCross)
+	p.SetTurbo(gpsx.ButtonSquare, true)
+	p.SetStickScale(150, 100)
+
+	p.Run()
+}
@@ -0,0 +1,127 @@
+// Example program archiving a PS2 memory card over USB serial.
+// Target: Raspberry Pi Pico
+//
+// Cards requiring MagicGate authentication (official Sony cards) are not
+// supported.
+//
+// Serial commands (one character):
+//
+//	s: print the card geometry
+//	d: dump the card as raw pages with spare areas (528 bytes per page)
+//	r: restore raw pages sent right after the command
+//
+// Errors are reported on UART0 (GP0) rather than USB serial, so they never
+// end up in the middle of a dump. A dump stops at the first unreadable page.
+package main
+
+import (
+	"machine"
+	"strconv"
+	"time"
+
+	"gpsx"
+	"gpsx/ps2mc"
+)
+
+func main() {
+	// Configure pins for Raspberry Pi Pico
+	pins := gpsx.PinConfig{
+		DAT: machine.GP2, // Data input (requires external 1k pull-up)
+		CMD: machine.GP3, // Command output
+		CLK: machine.GP4, // Clock output
+		AT1: machine.GP5, // Attention for slot 1
+		ACK: machine.GP7, // ACK input (memory cards acknowledge late)
+	}
+
+	psx := gpsx.New(gpsx.PS2, pins)
+	card := psx.PS2MemoryCard(gpsx.Pad1)
+
+	serial := machine.Serial
+	diag := machine.UART0
+	diag.Configure(machine.UARTConfig{BaudRate: 115200})
+
+	for {
+		cmd, err := serial.ReadByte()
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		if !card.Present() {
+			report(diag, "no memory card", 0, nil)
+			continue
+		}
+		specs, err := card.Specs()
+		if err != nil {
+			report(diag, "specs error", 0, err)
+			continue
+		}
+
+		switch cmd {
+		case 's':
+			println("page size:", specs.PageSize)
+			println("pages per block:", specs.PagesPerBlock)
+			println("pages:", specs.Pages)
+			println("size:", specs.Size())
+		case 'd':
+			if p, err := dump(card, specs, serial); err != nil {
+				report(diag, "read error at page", p, err)
+			}
+		case 'r':
+			if p, err := restore(card, specs, serial); err != nil {
+				report(diag, "write error at page", p, err)
+			} else {
+				println("restore done")
+			}
+		}
+	}
+}
+
+// report prints an error on the diagnostics UART.
+func report(diag *machine.UART, msg string, page uint32, err error) {
+	diag.Write([]byte(msg))
+	if err != nil {
+		diag.Write([]byte(" " + strconv.Itoa(int(page)) + ": " + err.Error()))
+	}
+	diag.Write([]byte("\r\n"))
+}
+
+// dump sends every page followed by its spare area. It stops at the first
+// page that cannot be read and returns its number.
+func dump(card *gpsx.PS2MemoryCard, specs ps2mc.Specs, serial machine.Serialer) (uint32, error) {
+	var page [ps2mc.RawPageSize]byte
+	for p := uint32(0); p < specs.Pages; p++ {
+		if err := card.ReadPage(p, page[:ps2mc.PageSize], page[ps2mc.PageSize:]); err != nil {
+			return p, err
+		}
+		serial.Write(page[:])
+	}
+	return 0, nil
+}
+
+// restore erases each block and writes the pages received over serial.
+// The received spare areas are ignored, the ECC is generated again.
+func restore(card *gpsx.PS2MemoryCard, specs ps2mc.Specs, serial machine.Serialer) (uint32, error) {
+	var page [ps2mc.RawPageSize]byte
+	for p := uint32(0); p < specs.Pages; p++ {
+		for i := range page {
+			for {
+				b, err := serial.ReadByte()
+				if err == nil {
+					page[i] = b
+					break
+				}
+			}
+		}
+
+		if p%uint32(specs.PagesPerBlock) == 0 {
+			if err := card.EraseBlock(p / uint32(specs.PagesPerBlock)); err != nil {
+				return p, err
+			}
+		}
+		if err := card.WritePage(p, page[:ps2mc.PageSize]); err != nil {
+			return p, err
+		}
+	}
+	return 0, nil
+}
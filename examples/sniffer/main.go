@@ -0,0 +1,54 @@
+// Example program watching a console talk to its controller.
+// Target: Raspberry Pi Pico
+//
+// Tap the controller port with all pins as inputs (no pull-ups needed,
+// the console and pad provide them) and share ground. Every frame is
+// decoded and printed over USB serial: mode changes, motor mapping and
+// pressure masks as the game sends them, and polls whenever the buttons
+// or rumble change. Timing faults found while decoding are flagged.
+package main
+
+import (
+	"machine"
+
+	"gpsx"
+	"gpsx/bus"
+)
+
+func main() {
+	pins := gpsx.SnifferPinConfig{
+		ATT: machine.GP10,
+		CLK: machine.GP11,
+		CMD: machine.GP12,
+		DAT: machine.GP13,
+		ACK: machine.GP14,
+	}
+	sniffer := gpsx.NewSniffer(pins)
+
+	// Last poll printed: motor bytes and button bytes
+	var last [4]byte
+
+	for {
+		f, _ := sniffer.Next(0)
+		if len(f.Cmd) < 5 {
+			continue
+		}
+
+		// Polls arrive every frame; only print changes
+		if f.Cmd[0] == bus.AddressController && f.Cmd[1] == 0x42 {
+			poll := [4]byte{f.Cmd[3], f.Cmd[4], f.Resp[3], f.Resp[4]}
+			if poll == last {
+				continue
+			}
+			last = poll
+		}
+
+		println(f.Time.Milliseconds(), bus.Describe(f))
+		if f.Cmd[0] == bus.AddressController && (f.Cmd[1] == 0x42 || f.Resp[1] != 0xF3) {
+			println("  held:", gpsx.ParseButtons(f.Resp).String())
+		}
+		for _, v := range sniffer.Violations() {
+			println("  !", v.String())
+		}
+	}
+}
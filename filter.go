@@ -0,0 +1,133 @@
+package gpsx
+
+import "time"
+
+// maxStableGroups is the number of stable-combination groups per pad.
+const maxStableGroups = 4
+
+// stableGroup is a set of contacts that only change together.
+type stableGroup struct {
+	mask  Buttons
+	polls uint8
+	last  Buttons // Raw pattern seen on the last poll
+	count uint8   // Polls the pattern has been seen in a row
+}
+
+// buttonFilter debounces the buttons of one pad.
+type buttonFilter struct {
+	polls  [16]uint8         // Polls a new state must last, 0 or 1 = off
+	stable [16]time.Duration // Time a new state must last
+	groups [maxStableGroups]stableGroup
+
+	out   Buttons // Filtered buttons
+	count [16]uint8
+	since [16]time.Time
+}
+
+// SetDebounce makes a change of btn on pad wait until it has been seen on
+// polls consecutive polls and has lasted at least d before IsDown,
+// Pressed, events and callbacks see it. Polls of 0 or 1 and a zero d
+// turn the filter off. This hides contact chatter on worn pads, at the
+// cost of polls-1 polls of latency.
+func (g *GPSX) SetDebounce(pad uint8, btn Button, polls uint8, d time.Duration) {
+	for i, e := range buttonTable {
+		if e.btn == btn {
+			g.filters[pad].polls[i] = polls
+			g.filters[pad].stable[i] = d
+		}
+	}
+}
+
+// SetDebounceAll sets the same debounce for every button of pad.
+func (g *GPSX) SetDebounceAll(pad uint8, polls uint8, d time.Duration) {
+	for i := range buttonTable {
+		g.filters[pad].polls[i] = polls
+		g.filters[pad].stable[i] = d
+	}
+}
+
+// SetStableGroup makes the buttons of group on pad change together, and
+// only once their combination has read the same on polls consecutive
+// polls. It is meant for encoders that report a position on several
+// contacts, like the Densha de GO! handles, which pass through
+// meaningless combinations between positions. Buttons in a group skip
+// their per-button debounce. Polls of 0 removes the group. It returns
+// false if the pad already has maxStableGroups groups.
+func (g *GPSX) SetStableGroup(pad uint8, group Buttons, polls uint8) bool {
+	f := &g.filters[pad]
+	for i := range f.groups {
+		if f.groups[i].mask == group {
+			if polls == 0 {
+				f.groups[i] = stableGroup{}
+			} else {
+				f.groups[i].polls = polls
+			}
+			return true
+		}
+	}
+	if polls == 0 {
+		return true
+	}
+	for i := range f.groups {
+		if f.groups[i].mask == 0 {
+			f.groups[i] = stableGroup{mask: group, polls: polls}
+			return true
+		}
+	}
+	return false
+}
+
+// filter debounces the raw buttons of a poll and returns the buttons to
+// report. first is set on the first poll, which is taken as is.
+func (f *buttonFilter) filter(raw Buttons, now time.Time, first bool) Buttons {
+	if first {
+		f.out = raw
+		for i := range f.groups {
+			f.groups[i].last = raw & f.groups[i].mask
+		}
+		return raw
+	}
+
+	grouped := Buttons(0)
+	for i := range f.groups {
+		gr := &f.groups[i]
+		if gr.mask == 0 {
+			continue
+		}
+		grouped |= gr.mask
+
+		pattern := raw & gr.mask
+		if pattern != gr.last {
+			gr.last = pattern
+			gr.count = 0
+		}
+		if gr.count < gr.polls {
+			gr.count++
+		}
+		if gr.count >= gr.polls {
+			f.out = f.out&^gr.mask | pattern
+		}
+	}
+
+	for i := range f.count {
+		bit := Buttons(1) << i
+		if grouped&bit != 0 {
+			continue
+		}
+		if raw&bit == f.out&bit {
+			f.count[i] = 0
+			continue
+		}
+		if f.count[i] == 0 {
+			f.since[i] = now
+		}
+		if f.count[i] < 0xFF {
+			f.count[i]++
+		}
+		if f.count[i] >= f.polls[i] && now.Sub(f.since[i]) >= f.stable[i] {
+			f.out ^= bit
+			f.count[i] = 0
+		}
+	}
+	return f.out
+}
@@ -0,0 +1,169 @@
+package gpsx_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/sim"
+)
+
+// polls polls pad n times and returns whether btn read down on each.
+func polls(s *sim.Sim, pad uint8, btn gpsx.Button, n int) []bool {
+	var down []bool
+	for i := 0; i < n; i++ {
+		s.Poll(pad)
+		down = append(down, s.PSX.IsDown(pad, btn))
+	}
+	return down
+}
+
+func equal(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDebouncePolls(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.SetDebounce(gpsx.Pad1, gpsx.ButtonCross, 3, 0)
+	s.Poll(gpsx.Pad1)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	if got := polls(s, gpsx.Pad1, gpsx.ButtonCross, 3); !equal(got, []bool{false, false, true}) {
+		t.Errorf("press: %v, want down on the third poll", got)
+	}
+	if !s.PSX.Pressed(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Pressed not reported when the press passed")
+	}
+
+	// Release chatters back down after two polls: the count restarts
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	got := polls(s, gpsx.Pad1, gpsx.ButtonCross, 2)
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	got = append(got, polls(s, gpsx.Pad1, gpsx.ButtonCross, 1)...)
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	got = append(got, polls(s, gpsx.Pad1, gpsx.ButtonCross, 3)...)
+	if want := []bool{true, true, true, true, true, false}; !equal(got, want) {
+		t.Errorf("chattering release: %v, want %v", got, want)
+	}
+
+	// Other buttons are not filtered
+	s.Press(gpsx.Pad1, gpsx.ButtonCircle)
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCircle) {
+		t.Error("Circle debounced without a filter")
+	}
+}
+
+func TestDebounceTime(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	c := newClock(s)
+	s.PSX.SetDebounce(gpsx.Pad1, gpsx.ButtonCross, 0, 20*time.Millisecond)
+	s.Poll(gpsx.Pad1)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	var got []bool
+	for i := 0; i < 4; i++ {
+		c.step(s, gpsx.Pad1, 10*time.Millisecond)
+		got = append(got, s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross))
+	}
+	// First seen at 10ms, passes once it has lasted 20ms
+	if want := []bool{false, false, true, true}; !equal(got, want) {
+		t.Errorf("press: %v, want %v", got, want)
+	}
+
+	// A bounce restarts the timer
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	if !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("release passed 10ms after a bounce")
+	}
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("release not passed after 20ms")
+	}
+}
+
+func TestStableGroup(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	group := gpsx.ButtonsOf(gpsx.ButtonL1, gpsx.ButtonL2, gpsx.ButtonR1)
+	if !s.PSX.SetStableGroup(gpsx.Pad1, group, 2) {
+		t.Fatal("SetStableGroup failed")
+	}
+
+	held := func() gpsx.Buttons {
+		return s.PSX.State(gpsx.Pad1).Buttons.Intersect(group)
+	}
+
+	s.Press(gpsx.Pad1, gpsx.ButtonL1)
+	s.Poll(gpsx.Pad1)
+	if held() != gpsx.ButtonsOf(gpsx.ButtonL1) {
+		t.Fatalf("first poll: %v, want L1", held())
+	}
+
+	// Move from L1 to L2+R1 through L1+L2+R1 for one poll
+	s.Press(gpsx.Pad1, gpsx.ButtonL2)
+	s.Press(gpsx.Pad1, gpsx.ButtonR1)
+	s.Poll(gpsx.Pad1)
+	s.Release(gpsx.Pad1, gpsx.ButtonL1)
+	s.Poll(gpsx.Pad1)
+	if held() != gpsx.ButtonsOf(gpsx.ButtonL1) {
+		t.Errorf("in between: %v, want L1 kept", held())
+	}
+	s.Poll(gpsx.Pad1)
+	if held() != gpsx.ButtonsOf(gpsx.ButtonL2, gpsx.ButtonR1) {
+		t.Errorf("settled: %v, want L2+R1", held())
+	}
+
+	// Removing the group lets the contacts through directly
+	s.PSX.SetStableGroup(gpsx.Pad1, group, 0)
+	s.Release(gpsx.Pad1, gpsx.ButtonR1)
+	s.Poll(gpsx.Pad1)
+	if held() != gpsx.ButtonsOf(gpsx.ButtonL2) {
+		t.Errorf("after removing the group: %v, want L2", held())
+	}
+}
+
+func TestStableGroupSkipsDebounce(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.SetDebounceAll(gpsx.Pad1, 5, 0)
+	s.PSX.SetStableGroup(gpsx.Pad1, gpsx.ButtonsOf(gpsx.ButtonL1, gpsx.ButtonL2), 1)
+	s.Poll(gpsx.Pad1)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonL1)
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonL1) {
+		t.Error("grouped L1 held back by the per-button debounce")
+	}
+	if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Cross not debounced")
+	}
+}
+
+func TestStableGroupLimit(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	for i := 0; i < 4; i++ {
+		if !s.PSX.SetStableGroup(gpsx.Pad1, gpsx.Buttons(1)<<i, 2) {
+			t.Fatalf("group %d refused", i)
+		}
+	}
+	if s.PSX.SetStableGroup(gpsx.Pad1, gpsx.Buttons(1)<<8, 2) {
+		t.Error("fifth group accepted")
+	}
+	if !s.PSX.SetStableGroup(gpsx.Pad1, gpsx.Buttons(1)<<0, 3) {
+		t.Error("updating an existing group refused")
+	}
+}
@@ -3,8 +3,10 @@
 package gpsx
 
 import (
-	"machine"
+	"io"
 	"time"
+
+	"gpsx/bus"
 )
 
 // Platform type constants
@@ -40,32 +42,27 @@ const (
 	ModeUnlock  uint8 = 0x02
 )
 
+// Device ID constants (byte 1 of the poll response)
+const (
+	DeviceDigital     uint8 = 0x41 // Digital pad
+	DeviceAnalog      uint8 = 0x73 // Analog pad (DualShock)
+	DeviceFlightStick uint8 = 0x53 // PS1 Analog Joystick (SCPH-1110)
+	DeviceConfig      uint8 = 0xF3 // Pad in config mode
+)
+
 // State indices
 const (
 	stateCurrent  = 0
 	statePrevious = 1
 )
 
-// PinConfig holds the pin configuration for the PS2 controller interface.
-type PinConfig struct {
-	DAT machine.Pin // Data input (requires pull-up)
-	CMD machine.Pin // Command output
-	CLK machine.Pin // Clock output
-	AT1 machine.Pin // Attention for PAD1
-	AT2 machine.Pin // Attention for PAD2 (optional if using only PAD1)
-	ACK machine.Pin // ACK input (not used but defined)
-}
-
 // GPSX is the main controller interface.
 type GPSX struct {
 	psxType uint8
-	pins    PinConfig
+	bus     bus.Transport
 
-	// Timing configuration
-	waitAfterATT    time.Duration
+	// Pause after each command
 	commandInterval time.Duration
-	clkHalfCycle    time.Duration
-	ackWaitDuration time.Duration
 
 	// State management (2 pads x 2 states x buffer size)
 	keyState [2][2][PadBufferSize]byte
@@ -74,63 +71,60 @@ type GPSX struct {
 	// Motor levels for each pad
 	motor1Level [2]uint8
 	motor2Level [2]uint8
+
+	// Button events not yet taken by the application
+	events eventQueue
+
+	// Registered callbacks
+	handlers [maxHandlers]handler
+
+	// Debounce per pad
+	filters [2]buttonFilter
+
+	// Press timing per pad
+	holds          [2]holdTracker
+	repeatDelay    time.Duration
+	repeatRate     time.Duration
+	doubleTapDelay time.Duration
+
+	// Stick processing per pad and stick
+	sticks [2][2]StickConfig
+
+	// Stick calibration per pad, and the stick values before it
+	calibration [2]Calibration
+	rawSticks   [2][4]uint8
+
+	// Stick drift per pad
+	drift [2]driftTracker
+
+	// Time source for polls, nil for time.Now
+	clock func() time.Time
 }
 
-// New creates a new GPSX controller with the specified platform type and pins.
-func New(psxType uint8, pins PinConfig) *GPSX {
+// NewWithTransport creates a GPSX that talks to pads through t instead of
+// GPIO pins, such as a bus.Loopback wired to simulated pads. No pause is
+// made between commands; use SetCommandInterval if t needs one. If t is
+// a *bus.Replay, polls are timed by the recorded frame times (see
+// SetClock), so holds, repeats and debouncing behave as they did live.
+func NewWithTransport(psxType uint8, t bus.Transport) *GPSX {
 	g := &GPSX{
 		psxType: psxType,
-		pins:    pins,
+		bus:     t,
 	}
-
-	// Set timing based on platform
-	if psxType == PS1 {
-		g.waitAfterATT = 50 * time.Microsecond
-		g.commandInterval = 16 * time.Millisecond
-		g.clkHalfCycle = 2 * time.Microsecond
-		g.ackWaitDuration = 15 * time.Microsecond
-	} else { // PS2
-		g.waitAfterATT = 15 * time.Microsecond
-		g.commandInterval = 10 * time.Millisecond
-		g.clkHalfCycle = 1 * time.Microsecond
-		g.ackWaitDuration = 15 * time.Microsecond
+	if r, ok := t.(*bus.Replay); ok {
+		g.clock = r.Clock(time.Now())
 	}
 
 	// Initialize motor levels
 	g.motor1Level = [2]uint8{Motor1Off, Motor1Off}
 	g.motor2Level = [2]uint8{0x00, 0x00}
 
-	g.init()
+	g.repeatDelay = defaultRepeatDelay
+	g.repeatRate = defaultRepeatRate
+	g.doubleTapDelay = defaultDoubleTapDelay
 	return g
 }
 
-// init configures the GPIO pins and sets their initial states.
-func (g *GPSX) init() {
-	// Configure pins
-	g.pins.CLK.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	g.pins.CMD.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	g.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinInput})
-	g.pins.AT1.Configure(machine.PinConfig{Mode: machine.PinOutput})
-
-	// Configure AT2 only if it's set (non-zero)
-	if g.pins.AT2 != 0 {
-		g.pins.AT2.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	}
-
-	// Configure ACK only if it's set (non-zero)
-	if g.pins.ACK != 0 {
-		g.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
-	}
-
-	// Set initial pin states
-	g.pins.CLK.High()
-	g.pins.CMD.Low()
-	g.pins.AT1.High()
-	if g.pins.AT2 != 0 {
-		g.pins.AT2.High()
-	}
-}
-
 // UpdateState polls the controller and updates button states.
 func (g *GPSX) UpdateState(pad uint8) {
 	// Swap current and previous states (copy in Go)
@@ -147,10 +141,44 @@ func (g *GPSX) UpdateState(pad uint8) {
 		g.keyState[pad][stateCurrent][i] = g.padState[i]
 	}
 
+	now := g.now()
+	g.calibrate(pad, now)
+
+	// Debounce the buttons before anything looks at them
+	cur := &g.keyState[pad][stateCurrent]
+	buttons := g.filters[pad].filter(ParseButtons(cur[:]), now, g.keyState[pad][statePrevious][1] == 0)
+	cur[3] = ^uint8(buttons)
+	cur[4] = ^uint8(buttons >> 8)
+
 	// For digital keys, previous state is stored as a mask (XOR)
 	// of bits changed from previous poll.
 	g.keyState[pad][statePrevious][3] ^= g.keyState[pad][stateCurrent][3]
 	g.keyState[pad][statePrevious][4] ^= g.keyState[pad][stateCurrent][4]
+
+	g.trackHolds(pad, now)
+	g.queueEvents(pad, now)
+	g.dispatch(pad, now)
+}
+
+// SetCommandInterval sets the pause after each command. The default
+// gives slow pads time to recover between commands; code that must fit
+// its polls between other bus work can shorten it.
+func (g *GPSX) SetCommandInterval(d time.Duration) {
+	g.commandInterval = d
+}
+
+// SetClock sets the time source used to time polls, for holds, repeats,
+// debouncing and drift tracking. SetClock(nil) restores time.Now.
+func (g *GPSX) SetClock(now func() time.Time) {
+	g.clock = now
+}
+
+// now returns the time of the poll in progress.
+func (g *GPSX) now() time.Time {
+	if g.clock != nil {
+		return g.clock()
+	}
+	return time.Now()
 }
 
 // Motor sets the motor levels (takes effect on next UpdateState).
@@ -171,12 +199,56 @@ func (g *GPSX) MotorEnable(pad uint8, motor1Enable uint8, motor2Enable uint8) {
 }
 
 // Mode sets the analog/digital mode and lock state.
-func (g *GPSX) Mode(pad uint8, mode uint8, lock uint8) {
+// It returns false if the pad does not support config commands. The PS1
+// Analog Joystick is one of these: its mode is selected by a hardware
+// switch, so the commands are ignored and the pad keeps reporting 0x53.
+func (g *GPSX) Mode(pad uint8, mode uint8, lock uint8) bool {
 	cmdADMode := []byte{0x01, 0x44, 0x00, mode, lock, 0x00, 0x00, 0x00, 0x00}
 	cmdEnterCfg := []byte{0x01, 0x43, 0x00, 0x01}
 	cmdExitCfg := []byte{0x01, 0x43, 0x00, 0x00, 0x5A, 0x5A, 0x5A, 0x5A, 0x5A}
 
 	g.sendCommand(pad, cmdEnterCfg)
 	g.sendCommand(pad, cmdADMode)
+
+	// Only a pad that entered config mode answers with DeviceConfig
+	supported := g.padState[1] == DeviceConfig
+
 	g.sendCommand(pad, cmdExitCfg)
+	return supported
+}
+
+// Record starts writing every frame exchanged with the pads to w as a
+// transcript (see bus.Recorder), replacing any recording in progress.
+// Record(nil) stops recording. A transcript can be fed back to the driver
+// with bus.NewReplay and NewWithTransport to reproduce what it saw.
+func (g *GPSX) Record(w io.Writer) *bus.Recorder {
+	if r, ok := g.bus.(*bus.Recorder); ok {
+		g.bus = r.Transport()
+	}
+	if w == nil {
+		return nil
+	}
+	r := bus.NewRecorder(g.bus, w)
+	g.bus = r
+	return r
+}
+
+// SupportsConfig reports whether the pad answers config commands, as a
+// DualShock does and an original digital pad does not. It enters config
+// mode and leaves it again, keeping the current analog/digital mode.
+func (g *GPSX) SupportsConfig(pad uint8) bool {
+	cmdEnterCfg := []byte{0x01, 0x43, 0x00, 0x01}
+	cmdPoll := []byte{0x01, 0x42, 0x00, 0x00, 0x00}
+	cmdExitCfg := []byte{0x01, 0x43, 0x00, 0x00, 0x5A, 0x5A, 0x5A, 0x5A, 0x5A}
+
+	g.sendCommand(pad, cmdEnterCfg)
+
+	// Only a pad that entered config mode answers with DeviceConfig
+	g.sendCommand(pad, cmdPoll)
+	supported := g.padState[1] == DeviceConfig
+
+	if supported {
+		g.sendCommand(pad, cmdExitCfg)
+	}
+	return supported
 }
@@ -0,0 +1,240 @@
+package gpsx_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/bus"
+	"gpsx/sim"
+)
+
+// fakeClock is a time source for SetClock that only moves when told.
+type fakeClock struct {
+	t time.Time
+}
+
+// newClock sets a fakeClock on the driver of s.
+func newClock(s *sim.Sim) *fakeClock {
+	c := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s.PSX.SetClock(c.now)
+	return c
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+// step advances the clock by d and polls pad.
+func (c *fakeClock) step(s *sim.Sim, pad uint8, d time.Duration) {
+	c.t = c.t.Add(d)
+	s.Poll(pad)
+}
+
+// rawFrame sends msg to a pad straight over the simulated bus and returns
+// the reply, for commands the driver has no method for.
+func rawFrame(s *sim.Sim, pad uint8, msg ...byte) []byte {
+	resp := make([]byte, len(msg))
+	s.Bus.Begin(pad)
+	for i, b := range msg {
+		resp[i] = s.Bus.Transfer(b)
+	}
+	s.Bus.End(pad)
+	return resp
+}
+
+func TestButtonEdges(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.Poll(gpsx.Pad1)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) || !s.PSX.Pressed(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Cross not pressed after the press")
+	}
+	if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCircle) {
+		t.Error("press seen on another button")
+	}
+
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) || s.PSX.Pressed(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Pressed repeated while held")
+	}
+
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	s.Poll(gpsx.Pad1)
+	if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) || !s.PSX.Released(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Cross not released after the release")
+	}
+
+	s.Poll(gpsx.Pad1)
+	if s.PSX.Released(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("Released repeated")
+	}
+}
+
+func TestModeAndSticks(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.Sticks(gpsx.Pad1, 0x10, 0x20, 0xE0, 0xF0)
+
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDigital(gpsx.Pad1) || s.PSX.IsAnalog(gpsx.Pad1) {
+		t.Fatalf("new pad reports ID %02X, want digital", s.PSX.DeviceID(gpsx.Pad1))
+	}
+
+	if !s.PSX.SupportsConfig(gpsx.Pad1) {
+		t.Error("SupportsConfig false for a DualShock")
+	}
+	if s.Pads[0].Config() {
+		t.Error("pad left in config mode by SupportsConfig")
+	}
+
+	if !s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock) {
+		t.Fatal("Mode reports config unsupported")
+	}
+	if !s.Pads[0].Analog() || !s.Pads[0].Locked() || s.Pads[0].Config() {
+		t.Errorf("pad analog %v locked %v config %v, want analog, locked, out of config",
+			s.Pads[0].Analog(), s.Pads[0].Locked(), s.Pads[0].Config())
+	}
+
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsAnalog(gpsx.Pad1) || s.PSX.DeviceID(gpsx.Pad1) != gpsx.DeviceAnalog {
+		t.Fatalf("ID %02X after Mode, want %02X", s.PSX.DeviceID(gpsx.Pad1), gpsx.DeviceAnalog)
+	}
+	got := [4]uint8{
+		s.PSX.AnalogRightX(gpsx.Pad1), s.PSX.AnalogRightY(gpsx.Pad1),
+		s.PSX.AnalogLeftX(gpsx.Pad1), s.PSX.AnalogLeftY(gpsx.Pad1),
+	}
+	if want := [4]uint8{0x10, 0x20, 0xE0, 0xF0}; got != want {
+		t.Errorf("sticks % X, want % X", got, want)
+	}
+
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeDigital, gpsx.ModeUnlock)
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsDigital(gpsx.Pad1) || s.Pads[0].Locked() {
+		t.Errorf("ID %02X after switching back, want digital and unlocked", s.PSX.DeviceID(gpsx.Pad1))
+	}
+}
+
+func TestMotors(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+
+	// Unmapped motors stay off whatever the poll asks for
+	s.PSX.Motor(gpsx.Pad1, gpsx.Motor1On, 0xC0)
+	s.Poll(gpsx.Pad1)
+	if small, large := s.Pads[0].Motors(); small || large != 0 {
+		t.Errorf("unmapped motors running: %v %02X", small, large)
+	}
+
+	s.PSX.MotorEnable(gpsx.Pad1, gpsx.Motor1Enable, gpsx.Motor2Enable)
+	s.Poll(gpsx.Pad1)
+	if small, large := s.Pads[0].Motors(); !small || large != 0xC0 {
+		t.Errorf("motors %v %02X, want on C0", small, large)
+	}
+
+	s.PSX.Motor(gpsx.Pad1, gpsx.Motor1Off, 0x00)
+	s.Poll(gpsx.Pad1)
+	if small, large := s.Pads[0].Motors(); small || large != 0 {
+		t.Errorf("motors %v %02X after stopping", small, large)
+	}
+}
+
+func TestPressure(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+
+	// Enable all pressure bytes with 0x4F in config mode
+	rawFrame(s, gpsx.Pad1, 0x01, 0x43, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00)
+	reply := rawFrame(s, gpsx.Pad1, 0x01, 0x4F, 0x00, 0xFF, 0xFF, 0x03, 0x00, 0x00, 0x00)
+	if reply[1] != gpsx.DeviceConfig || reply[8] != 0x5A {
+		t.Errorf("0x4F reply % X", reply)
+	}
+	rawFrame(s, gpsx.Pad1, 0x01, 0x43, 0x00, 0x00, 0x5A, 0x5A, 0x5A, 0x5A, 0x5A)
+
+	s.Pads[0].SetPressure(gpsx.ButtonCross, 0x40)
+	s.Pads[0].SetButton(gpsx.ButtonTriangle, true)
+
+	poll := make([]byte, 21)
+	poll[0], poll[1] = 0x01, 0x42
+	reply = rawFrame(s, gpsx.Pad1, poll...)
+	if reply[1] != 0x79 {
+		t.Fatalf("ID %02X in pressure mode, want 79", reply[1])
+	}
+	// Pressures from byte 9: Right, Left, Up, Down, Triangle, Circle, Cross, ...
+	if reply[13] != 0xFF || reply[15] != 0x40 || reply[14] != 0x00 {
+		t.Errorf("pressures % X", reply[9:])
+	}
+
+	// The driver still reads the buttons and sticks of a pressure poll
+	s.Poll(gpsx.Pad1)
+	if !s.PSX.IsAnalog(gpsx.Pad1) || !s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonTriangle) {
+		t.Errorf("ID %02X, Triangle down %v", s.PSX.DeviceID(gpsx.Pad1), s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonTriangle))
+	}
+}
+
+func TestPadsAreSeparate(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.PSX.Mode(gpsx.Pad2, gpsx.ModeAnalog, gpsx.ModeLock)
+	s.Press(gpsx.Pad2, gpsx.ButtonStart)
+
+	s.Poll(gpsx.Pad1)
+	s.Poll(gpsx.Pad2)
+	if s.PSX.IsAnalog(gpsx.Pad1) || !s.PSX.IsAnalog(gpsx.Pad2) {
+		t.Error("mode applied to the wrong pad")
+	}
+	if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonStart) || !s.PSX.IsDown(gpsx.Pad2, gpsx.ButtonStart) {
+		t.Error("button reported on the wrong pad")
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	var buf bytes.Buffer
+	s.PSX.Record(&buf)
+	s.Poll(gpsx.Pad1)
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	for i := 0; i < 5; i++ {
+		s.Poll(gpsx.Pad1)
+	}
+	s.PSX.Record(nil)
+
+	frames, err := bus.ReadTranscript(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 6 {
+		t.Fatalf("recorded %d frames, want 6", len(frames))
+	}
+
+	// Space the polls out as if they had been recorded 200ms apart
+	for i := range frames {
+		frames[i].Time = time.Duration(i) * 200 * time.Millisecond
+	}
+
+	replay := bus.NewReplay(frames)
+	g := gpsx.NewWithTransport(gpsx.PS2, replay)
+	g.UpdateState(gpsx.Pad1)
+	start := g.PollTime(gpsx.Pad1)
+	g.UpdateState(gpsx.Pad1)
+	if !g.Pressed(gpsx.Pad1, gpsx.ButtonCross) {
+		t.Error("press not replayed")
+	}
+	for i := 0; i < 4; i++ {
+		g.UpdateState(gpsx.Pad1)
+	}
+
+	if err := replay.Err(); err != nil || !replay.Done() {
+		t.Fatalf("replay: err %v, done %v", err, replay.Done())
+	}
+	if d := g.PollTime(gpsx.Pad1).Sub(start); d != time.Second {
+		t.Errorf("replayed polls span %v, want 1s", d)
+	}
+	if d := g.HeldFor(gpsx.Pad1, gpsx.ButtonCross); d != 800*time.Millisecond {
+		t.Errorf("HeldFor %v, want 800ms", d)
+	}
+	if !g.LongPressed(gpsx.Pad1, gpsx.ButtonCross, 700*time.Millisecond) {
+		t.Error("LongPressed not reached with the recorded timing")
+	}
+}
@@ -0,0 +1,129 @@
+package gpsx
+
+import "time"
+
+// maxHandlers is the number of callbacks that can be registered at once.
+// Handlers live in a fixed table so dispatching never allocates.
+const maxHandlers = 16
+
+// ButtonHandler is called with the pad and button of a button event.
+type ButtonHandler func(pad uint8, btn Button)
+
+// ModeHandler is called with the pad and its new device ID when the pad
+// changes mode (see DeviceID).
+type ModeHandler func(pad uint8, id uint8)
+
+// handlerKind selects what a handler reacts to.
+type handlerKind uint8
+
+const (
+	handlerNone handlerKind = iota
+	handlerPress
+	handlerRelease
+	handlerHold
+	handlerMode
+)
+
+// handler is one registered callback.
+type handler struct {
+	kind handlerKind
+	pad  uint8
+	btn  Button
+	fn   ButtonHandler
+	mode ModeHandler
+
+	// Hold handlers: how long, and progress on the current press
+	hold  time.Duration
+	since time.Time
+	fired bool
+}
+
+// OnPress registers fn to run from UpdateState when btn is pressed on pad.
+// It returns false if the handler table is full.
+func (g *GPSX) OnPress(pad uint8, btn Button, fn ButtonHandler) bool {
+	return g.addHandler(handler{kind: handlerPress, pad: pad, btn: btn, fn: fn})
+}
+
+// OnRelease registers fn to run from UpdateState when btn is released on
+// pad. It returns false if the handler table is full.
+func (g *GPSX) OnRelease(pad uint8, btn Button, fn ButtonHandler) bool {
+	return g.addHandler(handler{kind: handlerRelease, pad: pad, btn: btn, fn: fn})
+}
+
+// OnHold registers fn to run once per press, from the first UpdateState
+// that finds btn held on pad for at least d. It returns false if the
+// handler table is full.
+func (g *GPSX) OnHold(pad uint8, btn Button, d time.Duration, fn ButtonHandler) bool {
+	return g.addHandler(handler{kind: handlerHold, pad: pad, btn: btn, fn: fn, hold: d})
+}
+
+// OnModeChange registers fn to run from UpdateState when the device ID of
+// pad changes, as when the Analog button is pressed or another kind of
+// pad is plugged in. It returns false if the handler table is full.
+func (g *GPSX) OnModeChange(pad uint8, fn ModeHandler) bool {
+	return g.addHandler(handler{kind: handlerMode, pad: pad, mode: fn})
+}
+
+// ClearHandlers removes every registered handler.
+func (g *GPSX) ClearHandlers() {
+	for i := range g.handlers {
+		g.handlers[i] = handler{}
+	}
+}
+
+// addHandler stores h in the first free slot.
+func (g *GPSX) addHandler(h handler) bool {
+	for i := range g.handlers {
+		if g.handlers[i].kind == handlerNone {
+			g.handlers[i] = h
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch runs the handlers for what changed on the last poll of pad.
+func (g *GPSX) dispatch(pad uint8, now time.Time) {
+	changed, down, ok := g.edges(pad)
+	if !ok {
+		return
+	}
+	id := g.keyState[pad][stateCurrent][1]
+	modeChanged := g.keyState[pad][statePrevious][1] != id
+
+	for i := range g.handlers {
+		h := &g.handlers[i]
+		if h.kind == handlerNone || h.pad != pad {
+			continue
+		}
+
+		mask := h.btn.Mask()
+		switch h.kind {
+		case handlerPress:
+			if changed&down&mask != 0 {
+				h.fn(pad, h.btn)
+			}
+		case handlerRelease:
+			if changed&^down&mask != 0 {
+				h.fn(pad, h.btn)
+			}
+		case handlerHold:
+			if down&mask == 0 {
+				h.since = time.Time{}
+				h.fired = false
+				continue
+			}
+			if h.since.IsZero() {
+				h.since = now
+			}
+			if !h.fired && now.Sub(h.since) >= h.hold {
+				h.fired = true
+				h.fn(pad, h.btn)
+			}
+		case handlerMode:
+			if modeChanged {
+				h.mode(pad, id)
+			}
+		}
+	}
+}
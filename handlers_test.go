@@ -0,0 +1,138 @@
+package gpsx_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+)
+
+func TestButtonHandlers(t *testing.T) {
+	s, c := eventSim()
+	var log []string
+	record := func(what string) gpsx.ButtonHandler {
+		return func(pad uint8, btn gpsx.Button) {
+			log = append(log, what+" "+btn.String())
+		}
+	}
+	s.PSX.OnPress(gpsx.Pad1, gpsx.ButtonCross, record("press"))
+	s.PSX.OnRelease(gpsx.Pad1, gpsx.ButtonCross, record("release"))
+	s.PSX.OnPress(gpsx.Pad2, gpsx.ButtonCross, record("pad 2"))
+	s.PSX.OnPress(gpsx.Pad1, gpsx.ButtonCircle, record("press"))
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+	s.Release(gpsx.Pad1, gpsx.ButtonCross)
+	s.Press(gpsx.Pad1, gpsx.ButtonCircle)
+	c.step(s, gpsx.Pad1, 10*time.Millisecond)
+
+	want := []string{"press Cross", "release Cross", "press Circle"}
+	if len(log) != len(want) {
+		t.Fatalf("handlers ran %q, want %q", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Errorf("handler %d: %q, want %q", i, log[i], want[i])
+		}
+	}
+}
+
+func TestHoldHandler(t *testing.T) {
+	s, c := eventSim()
+	var fired []time.Duration
+	start := c.now()
+	s.PSX.OnHold(gpsx.Pad1, gpsx.ButtonStart, 500*time.Millisecond, func(pad uint8, btn gpsx.Button) {
+		fired = append(fired, c.now().Sub(start))
+	})
+
+	s.Press(gpsx.Pad1, gpsx.ButtonStart)
+	c.hold(s, gpsx.Pad1, time.Second)
+	s.Release(gpsx.Pad1, gpsx.ButtonStart)
+	c.hold(s, gpsx.Pad1, 300*time.Millisecond)
+	// A short press does not fire
+	s.Press(gpsx.Pad1, gpsx.ButtonStart)
+	c.hold(s, gpsx.Pad1, 300*time.Millisecond)
+	s.Release(gpsx.Pad1, gpsx.ButtonStart)
+	c.step(s, gpsx.Pad1, 100*time.Millisecond)
+	s.Press(gpsx.Pad1, gpsx.ButtonStart)
+	c.hold(s, gpsx.Pad1, 600*time.Millisecond)
+
+	want := []time.Duration{600 * time.Millisecond, 2300 * time.Millisecond}
+	if len(fired) != len(want) {
+		t.Fatalf("hold fired at %v, want %v", fired, want)
+	}
+	for i := range want {
+		if fired[i] != want[i] {
+			t.Errorf("hold %d fired at %v, want %v", i, fired[i], want[i])
+		}
+	}
+}
+
+func TestModeHandler(t *testing.T) {
+	s, _ := eventSim()
+	var ids []uint8
+	s.PSX.OnModeChange(gpsx.Pad1, func(pad uint8, id uint8) {
+		ids = append(ids, id)
+	})
+
+	s.Poll(gpsx.Pad1)
+	if len(ids) != 0 {
+		t.Fatalf("mode handler ran without a change: % X", ids)
+	}
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+	s.Poll(gpsx.Pad1)
+	s.Poll(gpsx.Pad1)
+	if len(ids) != 1 || ids[0] != gpsx.DeviceAnalog {
+		t.Errorf("mode handler ran with % X, want %02X once", ids, gpsx.DeviceAnalog)
+	}
+}
+
+func TestHandlerTableFull(t *testing.T) {
+	s, _ := eventSim()
+	fn := func(pad uint8, btn gpsx.Button) {}
+	n := 0
+	for s.PSX.OnPress(gpsx.Pad1, gpsx.ButtonCross, fn) {
+		n++
+		if n > 100 {
+			t.Fatal("handler table never fills")
+		}
+	}
+	if n != 16 {
+		t.Errorf("table took %d handlers, want 16", n)
+	}
+	if s.PSX.OnModeChange(gpsx.Pad1, func(uint8, uint8) {}) {
+		t.Error("OnModeChange succeeded on a full table")
+	}
+
+	s.PSX.ClearHandlers()
+	if !s.PSX.OnRelease(gpsx.Pad1, gpsx.ButtonCross, fn) {
+		t.Error("OnRelease failed after ClearHandlers")
+	}
+}
+
+func TestDispatchAllocs(t *testing.T) {
+	s, _ := eventSim()
+	n := 0
+	count := func(pad uint8, btn gpsx.Button) { n++ }
+	s.PSX.OnPress(gpsx.Pad1, gpsx.ButtonCross, count)
+	s.PSX.OnRelease(gpsx.Pad1, gpsx.ButtonCross, count)
+	s.PSX.OnHold(gpsx.Pad1, gpsx.ButtonCross, 0, count)
+	s.PSX.OnModeChange(gpsx.Pad1, func(uint8, uint8) { n++ })
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if s.PSX.IsDown(gpsx.Pad1, gpsx.ButtonCross) {
+			s.Release(gpsx.Pad1, gpsx.ButtonCross)
+		} else {
+			s.Press(gpsx.Pad1, gpsx.ButtonCross)
+		}
+		s.PSX.UpdateState(gpsx.Pad1)
+		s.PSX.NextEvent()
+	})
+	if allocs != 0 {
+		t.Errorf("UpdateState with handlers made %v allocations, want 0", allocs)
+	}
+	if n == 0 {
+		t.Error("handlers never ran")
+	}
+}
@@ -0,0 +1,148 @@
+package gpsx
+
+import "time"
+
+// Default auto-repeat and double-tap timing
+const (
+	defaultRepeatDelay    = 500 * time.Millisecond
+	defaultRepeatRate     = 100 * time.Millisecond
+	defaultDoubleTapDelay = 300 * time.Millisecond
+)
+
+// holdTracker follows press times of one pad's buttons.
+type holdTracker struct {
+	now  time.Time // Time of the last poll
+	prev time.Time // Time of the poll before it
+
+	pressedAt [16]time.Time // When each held button went down
+	lastTap   [16]time.Time // Last press that can start a double tap
+	doubleTap Buttons       // Double taps completed on the last poll
+}
+
+// update records the buttons that changed on a poll made at now.
+func (h *holdTracker) update(changed, down Buttons, now time.Time, first bool) {
+	h.prev = h.now
+	h.now = now
+	if first {
+		h.prev = now
+		changed = down
+	}
+
+	h.doubleTap = 0
+	for i := range h.pressedAt {
+		bit := Buttons(1) << i
+		if changed&bit == 0 {
+			continue
+		}
+		if down&bit == 0 {
+			h.pressedAt[i] = time.Time{}
+			continue
+		}
+		h.pressedAt[i] = now
+	}
+}
+
+// heldAt returns how long button i had been held at time t.
+func (h *holdTracker) heldAt(i int, t time.Time) time.Duration {
+	if h.pressedAt[i].IsZero() || t.Before(h.pressedAt[i]) {
+		return 0
+	}
+	return t.Sub(h.pressedAt[i])
+}
+
+// SetRepeat sets the auto-repeat timing used by Repeated: the first repeat
+// comes delay after the press, then one every rate. A zero rate turns
+// repeating off. The default is 500ms and 100ms.
+func (g *GPSX) SetRepeat(delay, rate time.Duration) {
+	g.repeatDelay = delay
+	g.repeatRate = rate
+}
+
+// SetDoubleTap sets the longest time between two presses of a button
+// that DoubleTapped counts as a double tap. The default is 300ms.
+func (g *GPSX) SetDoubleTap(window time.Duration) {
+	g.doubleTapDelay = window
+}
+
+// HeldFor returns how long btn had been held at the last poll, or zero
+// if it is up. Presses are timed from the poll that first saw them.
+func (g *GPSX) HeldFor(pad uint8, btn Button) time.Duration {
+	h := &g.holds[pad]
+	return h.heldAt(buttonIndex(btn), h.now)
+}
+
+// LongPressed returns true on the one poll where btn has been held for
+// d, so it fires once per press however often it is checked.
+func (g *GPSX) LongPressed(pad uint8, btn Button, d time.Duration) bool {
+	h := &g.holds[pad]
+	i := buttonIndex(btn)
+	if h.pressedAt[i].IsZero() || h.heldAt(i, h.now) < d {
+		return false
+	}
+	return h.pressedAt[i].Equal(h.now) || h.heldAt(i, h.prev) < d
+}
+
+// Repeated returns true when btn is pressed and then, while it is held,
+// on each poll that reaches an auto-repeat tick, like a key held on a
+// keyboard (see SetRepeat). Use it for scrolling through menus.
+func (g *GPSX) Repeated(pad uint8, btn Button) bool {
+	h := &g.holds[pad]
+	i := buttonIndex(btn)
+	if h.pressedAt[i].IsZero() {
+		return false
+	}
+	if h.pressedAt[i].Equal(h.now) {
+		return true
+	}
+	return g.repeatTicks(h.heldAt(i, h.now)) > g.repeatTicks(h.heldAt(i, h.prev))
+}
+
+// DoubleTapped returns true on the poll that sees the second of two
+// presses of btn within the double-tap window (see SetDoubleTap). A
+// third press starts a new pair.
+func (g *GPSX) DoubleTapped(pad uint8, btn Button) bool {
+	return g.holds[pad].doubleTap.Has(btn)
+}
+
+// repeatTicks returns the number of auto-repeats after holding for d.
+func (g *GPSX) repeatTicks(d time.Duration) int64 {
+	if g.repeatRate <= 0 || d < g.repeatDelay {
+		return 0
+	}
+	return 1 + int64((d-g.repeatDelay)/g.repeatRate)
+}
+
+// trackHolds updates press timing for the last poll of pad.
+func (g *GPSX) trackHolds(pad uint8, now time.Time) {
+	h := &g.holds[pad]
+	changed, down, ok := g.edges(pad)
+	if !ok {
+		down = ParseButtons(g.keyState[pad][stateCurrent][:])
+	}
+	h.update(changed, down, now, !ok)
+	if !ok {
+		return
+	}
+
+	pressed := changed & down
+	for i := range h.lastTap {
+		if pressed&(1<<i) == 0 {
+			continue
+		}
+		if !h.lastTap[i].IsZero() && now.Sub(h.lastTap[i]) <= g.doubleTapDelay {
+			h.doubleTap |= 1 << i
+			h.lastTap[i] = time.Time{}
+		} else {
+			h.lastTap[i] = now
+		}
+	}
+}
+
+// buttonIndex returns the bit of btn in a Buttons set.
+func buttonIndex(btn Button) int {
+	i := int(btn.byteIndex-3) * 8
+	for m := btn.bitMask; m > 1; m >>= 1 {
+		i++
+	}
+	return i & 0x0F
+}
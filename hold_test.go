@@ -0,0 +1,94 @@
+package gpsx_test
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+)
+
+func TestRepeated(t *testing.T) {
+	tests := []struct {
+		name       string
+		rate, poll time.Duration
+		want       []time.Duration // Polls where Repeated is true, from the first
+	}{
+		{"every poll", 100 * time.Millisecond, 50 * time.Millisecond, []time.Duration{0, 500, 600, 700}},
+		// A poll that passes two ticks repeats once
+		{"slow polls", 100 * time.Millisecond, 250 * time.Millisecond, []time.Duration{0, 500, 750}},
+		{"off", 0, 50 * time.Millisecond, []time.Duration{0}},
+	}
+	for _, tt := range tests {
+		s, c := eventSim()
+		s.PSX.SetRepeat(500*time.Millisecond, tt.rate)
+
+		s.Press(gpsx.Pad1, gpsx.ButtonDown)
+		var got []time.Duration
+		for d := time.Duration(0); d < 800*time.Millisecond; d += tt.poll {
+			c.step(s, gpsx.Pad1, tt.poll)
+			if s.PSX.Repeated(gpsx.Pad1, gpsx.ButtonDown) {
+				got = append(got, d/time.Millisecond)
+			}
+			if s.PSX.Repeated(gpsx.Pad1, gpsx.ButtonUp) {
+				t.Errorf("%s: Up repeated while Down held", tt.name)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: repeated at %v ms, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: repeated at %v ms, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+
+		s.Release(gpsx.Pad1, gpsx.ButtonDown)
+		c.step(s, gpsx.Pad1, tt.poll)
+		if s.PSX.Repeated(gpsx.Pad1, gpsx.ButtonDown) {
+			t.Errorf("%s: repeated after release", tt.name)
+		}
+	}
+}
+
+func TestDoubleTapped(t *testing.T) {
+	s, c := eventSim()
+	s.PSX.SetDoubleTap(300 * time.Millisecond)
+
+	// tap presses Cross after gap, holds it for 50ms and reports
+	// DoubleTapped on the press poll and the poll after it
+	tap := func(gap time.Duration) (onPress, after bool) {
+		s.Press(gpsx.Pad1, gpsx.ButtonCross)
+		c.step(s, gpsx.Pad1, gap)
+		onPress = s.PSX.DoubleTapped(gpsx.Pad1, gpsx.ButtonCross)
+		s.Release(gpsx.Pad1, gpsx.ButtonCross)
+		c.step(s, gpsx.Pad1, 50*time.Millisecond)
+		after = s.PSX.DoubleTapped(gpsx.Pad1, gpsx.ButtonCross)
+		return onPress, after
+	}
+
+	tests := []struct {
+		name string
+		gap  time.Duration // From the last release
+		want bool
+	}{
+		{"first", 50 * time.Millisecond, false},
+		{"second", 100 * time.Millisecond, true},
+		{"third starts a pair", 100 * time.Millisecond, false},
+		{"fourth ends it", 100 * time.Millisecond, true},
+		{"alone", 500 * time.Millisecond, false},
+		// 50ms held and 300ms apart is 350ms from the last press
+		{"too slow", 300 * time.Millisecond, false},
+		{"in time", 250 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		onPress, after := tap(tt.gap)
+		if onPress != tt.want {
+			t.Errorf("%s: DoubleTapped %v, want %v", tt.name, onPress, tt.want)
+		}
+		if after {
+			t.Errorf("%s: DoubleTapped still true on the next poll", tt.name)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package gpsx
+
+import (
+	"errors"
+	"time"
+)
+
+// Memory card geometry
+const (
+	MemoryCardFrameSize = 128  // Bytes per frame
+	MemoryCardFrames    = 1024 // Frames per card
+)
+
+// Memory card protocol constants
+const (
+	memoryCardAddress    byte = 0x81
+	memoryCardCmdRead    byte = 0x52 // 'R'
+	memoryCardCmdWrite   byte = 0x57 // 'W'
+	memoryCardID1        byte = 0x5A
+	memoryCardID2        byte = 0x5D
+	memoryCardCmdAck1    byte = 0x5C
+	memoryCardCmdAck2    byte = 0x5D
+	memoryCardEndGood    byte = 0x47 // 'G'
+	memoryCardEndBadSum  byte = 0x4E // 'N'
+	memoryCardEndBadAddr byte = 0xFF
+
+	// FLAG bit set until the first write after power on
+	memoryCardFlagFresh byte = 0x08
+
+	memoryCardReadLen  = 10 + MemoryCardFrameSize + 2
+	memoryCardWriteLen = 6 + MemoryCardFrameSize + 4
+
+	// Memory cards may hold ACK back while they access flash
+	memoryCardAckTimeout = 2 * time.Millisecond
+)
+
+// Memory card errors
+var (
+	ErrNoCard         = errors.New("gpsx: no memory card")
+	ErrCardFrame      = errors.New("gpsx: memory card frame out of range")
+	ErrCardBuffer     = errors.New("gpsx: memory card buffer too small")
+	ErrCardChecksum   = errors.New("gpsx: memory card checksum error")
+	ErrCardBadSector  = errors.New("gpsx: memory card bad sector")
+	ErrCardWrite      = errors.New("gpsx: memory card write failed")
+	ErrCardNoResponse = errors.New("gpsx: memory card stopped responding")
+)
+
+// MemoryCard accesses a PS1 memory card. The card shares the attention
+// line of the controller in the same slot, and is addressed with 0x81
+// instead of the controller's 0x01.
+//
+// Memory cards acknowledge late while they access flash, so connecting
+// the ACK pin is strongly recommended.
+type MemoryCard struct {
+	g    *GPSX
+	slot uint8
+	flag byte
+
+	msg  [memoryCardReadLen]byte
+	resp [memoryCardReadLen]byte
+}
+
+// MemoryCard returns the memory card in the slot of the given pad.
+func (g *GPSX) MemoryCard(slot uint8) *MemoryCard {
+	return &MemoryCard{
+		g:    g,
+		slot: slot,
+	}
+}
+
+// Present returns true if a memory card answers in the slot.
+func (c *MemoryCard) Present() bool {
+	// The read command header is enough to get the card ID,
+	// the transfer is simply abandoned afterwards.
+	msg := c.msg[:4]
+	msg[0] = memoryCardAddress
+	msg[1] = memoryCardCmdRead
+	msg[2] = 0x00
+	msg[3] = 0x00
+
+	n := c.g.exchange(c.slot, msg, c.resp[:], memoryCardAckTimeout)
+	if n < len(msg) || !c.checkID() {
+		return false
+	}
+	c.flag = c.resp[1]
+	return true
+}
+
+// Fresh returns true if the card has not been written since it was
+// powered on or inserted, as reported by the last access.
+func (c *MemoryCard) Fresh() bool {
+	return c.flag&memoryCardFlagFresh != 0
+}
+
+// ReadFrame reads a 128-byte frame into data.
+func (c *MemoryCard) ReadFrame(frame uint16, data []byte) error {
+	if frame >= MemoryCardFrames {
+		return ErrCardFrame
+	}
+	if len(data) < MemoryCardFrameSize {
+		return ErrCardBuffer
+	}
+
+	msg := c.msg[:memoryCardReadLen]
+	for i := range msg {
+		msg[i] = 0x00
+	}
+	msg[0] = memoryCardAddress
+	msg[1] = memoryCardCmdRead
+	msg[4] = byte(frame >> 8)
+	msg[5] = byte(frame)
+
+	n := c.g.exchange(c.slot, msg, c.resp[:], memoryCardAckTimeout)
+	if n < 4 || !c.checkID() {
+		return ErrNoCard
+	}
+	if n < len(msg) {
+		return ErrCardNoResponse
+	}
+	c.flag = c.resp[1]
+
+	resp := c.resp[:memoryCardReadLen]
+	if resp[6] != memoryCardCmdAck1 || resp[7] != memoryCardCmdAck2 {
+		return ErrCardNoResponse
+	}
+
+	// The card echoes the address, or 0xFFFF for a bad sector
+	if resp[8] != msg[4] || resp[9] != msg[5] {
+		return ErrCardBadSector
+	}
+
+	payload := resp[10 : 10+MemoryCardFrameSize]
+	if memoryCardChecksum(msg[4], msg[5], payload) != resp[10+MemoryCardFrameSize] {
+		return ErrCardChecksum
+	}
+	if resp[11+MemoryCardFrameSize] != memoryCardEndGood {
+		return ErrCardBadSector
+	}
+
+	copy(data, payload)
+	return nil
+}
+
+// WriteFrame writes a 128-byte frame from data.
+func (c *MemoryCard) WriteFrame(frame uint16, data []byte) error {
+	if frame >= MemoryCardFrames {
+		return ErrCardFrame
+	}
+	if len(data) < MemoryCardFrameSize {
+		return ErrCardBuffer
+	}
+
+	msg := c.msg[:memoryCardWriteLen]
+	for i := range msg {
+		msg[i] = 0x00
+	}
+	msg[0] = memoryCardAddress
+	msg[1] = memoryCardCmdWrite
+	msg[4] = byte(frame >> 8)
+	msg[5] = byte(frame)
+	copy(msg[6:], data[:MemoryCardFrameSize])
+	msg[6+MemoryCardFrameSize] = memoryCardChecksum(msg[4], msg[5], data[:MemoryCardFrameSize])
+
+	n := c.g.exchange(c.slot, msg, c.resp[:], memoryCardAckTimeout)
+	if n < 4 || !c.checkID() {
+		return ErrNoCard
+	}
+	if n < len(msg) {
+		return ErrCardNoResponse
+	}
+	c.flag = c.resp[1]
+
+	resp := c.resp[:memoryCardWriteLen]
+	if resp[7+MemoryCardFrameSize] != memoryCardCmdAck1 || resp[8+MemoryCardFrameSize] != memoryCardCmdAck2 {
+		return ErrCardNoResponse
+	}
+
+	switch resp[9+MemoryCardFrameSize] {
+	case memoryCardEndGood:
+		return nil
+	case memoryCardEndBadSum:
+		return ErrCardChecksum
+	case memoryCardEndBadAddr:
+		return ErrCardBadSector
+	default:
+		return ErrCardWrite
+	}
+}
+
+// checkID returns true if the last response carries the memory card ID.
+func (c *MemoryCard) checkID() bool {
+	return c.resp[2] == memoryCardID1 && c.resp[3] == memoryCardID2
+}
+
+// memoryCardChecksum returns the XOR checksum of a frame and its address.
+func memoryCardChecksum(msb byte, lsb byte, data []byte) byte {
+	sum := msb ^ lsb
+	for _, b := range data {
+		sum ^= b
+	}
+	return sum
+}
@@ -0,0 +1,285 @@
+package gpsx
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"gpsx/ps2mc"
+)
+
+// PS2 memory card commands
+const (
+	ps2CardCmdProbe        byte = 0x11
+	ps2CardCmdWriteEnd     byte = 0x12 // Commits a write or erase
+	ps2CardCmdEraseAddr    byte = 0x21
+	ps2CardCmdWriteAddr    byte = 0x22
+	ps2CardCmdReadAddr     byte = 0x23
+	ps2CardCmdGetSpecs     byte = 0x26
+	ps2CardCmdWriteData    byte = 0x42
+	ps2CardCmdReadData     byte = 0x43
+	ps2CardCmdReadWriteEnd byte = 0x81
+	ps2CardCmdErase        byte = 0x82
+
+	ps2CardReply     byte = 0x2B // Command accepted
+	ps2CardTermReady byte = 0x55 // Default terminator: operation succeeded
+)
+
+// Frame lengths
+const (
+	ps2CardSimpleLen = 4                   // 81 cmd 00 00
+	ps2CardAddrLen   = 9                   // 81 cmd a0 a1 a2 a3 xor 00 00
+	ps2CardSpecsLen  = 13                  // 81 26 + 2B size(2) block(2) pages(4) xor term
+	ps2CardDataLen   = ps2mc.ChunkSize + 6 // 81 cmd len data xor 00 00 (write) / 81 cmd len 00 2B data xor term (read)
+	ps2CardMaxLen    = ps2CardDataLen
+)
+
+// PS2 memory card errors
+var (
+	ErrCardECC      = errors.New("gpsx: memory card uncorrectable ECC error")
+	ErrCardProtocol = errors.New("gpsx: memory card unexpected reply")
+	ErrCardBusy     = errors.New("gpsx: memory card operation failed")
+)
+
+// PS2MemoryCard accesses a PS2 memory card page by page.
+//
+// Official cards require MagicGate authentication before they accept
+// page commands, which this driver does not implement. Cards that do
+// not enforce it, such as most third-party cards, can be dumped and
+// restored.
+type PS2MemoryCard struct {
+	g     *GPSX
+	slot  uint8
+	specs ps2mc.Specs
+
+	// ECC corrections made by ReadPage since the card was opened
+	Corrected int
+
+	msg  [ps2CardMaxLen]byte
+	resp [ps2CardMaxLen]byte
+}
+
+// PS2MemoryCard returns the PS2 memory card in the slot of the given pad.
+func (g *GPSX) PS2MemoryCard(slot uint8) *PS2MemoryCard {
+	return &PS2MemoryCard{
+		g:     g,
+		slot:  slot,
+		specs: ps2mc.DefaultSpecs,
+	}
+}
+
+// Present returns true if a PS2 memory card answers in the slot.
+func (c *PS2MemoryCard) Present() bool {
+	return c.simple(ps2CardCmdProbe) == nil
+}
+
+// Specs asks the card for its geometry. The result is also used to
+// check page and block numbers in later calls.
+func (c *PS2MemoryCard) Specs() (ps2mc.Specs, error) {
+	msg := c.command(ps2CardCmdGetSpecs, ps2CardSpecsLen)
+	if err := c.transfer(msg); err != nil {
+		return ps2mc.Specs{}, err
+	}
+
+	resp := c.resp[:len(msg)]
+	if resp[2] != ps2CardReply {
+		return ps2mc.Specs{}, ErrCardProtocol
+	}
+	if xor(resp[3:11]) != resp[11] {
+		return ps2mc.Specs{}, ErrCardChecksum
+	}
+	if resp[12] != ps2CardTermReady {
+		return ps2mc.Specs{}, ErrCardBusy
+	}
+
+	specs := ps2mc.Specs{
+		PageSize:      binary.LittleEndian.Uint16(resp[3:]),
+		PagesPerBlock: binary.LittleEndian.Uint16(resp[5:]),
+		Pages:         binary.LittleEndian.Uint32(resp[7:]),
+	}
+	if specs.PageSize != ps2mc.PageSize || specs.PagesPerBlock == 0 || specs.Pages == 0 {
+		return ps2mc.Specs{}, ErrCardProtocol
+	}
+	c.specs = specs
+	return specs, nil
+}
+
+// ReadPage reads a 512-byte page into data and its spare area into spare.
+// Single bit errors are corrected using the ECC in the spare area;
+// ErrCardECC is returned if the page is beyond repair.
+func (c *PS2MemoryCard) ReadPage(page uint32, data []byte, spare []byte) error {
+	if page >= c.specs.Pages {
+		return ErrCardFrame
+	}
+	if len(data) < ps2mc.PageSize || len(spare) < ps2mc.SpareSize {
+		return ErrCardBuffer
+	}
+
+	if err := c.setAddress(ps2CardCmdReadAddr, page); err != nil {
+		return err
+	}
+	for i := 0; i < ps2mc.ChunksPerPage; i++ {
+		if err := c.readData(data[i*ps2mc.ChunkSize : (i+1)*ps2mc.ChunkSize]); err != nil {
+			return err
+		}
+	}
+	if err := c.readData(spare[:ps2mc.SpareSize]); err != nil {
+		return err
+	}
+	if err := c.simple(ps2CardCmdReadWriteEnd); err != nil {
+		return err
+	}
+
+	switch ps2mc.CheckPage(data, spare) {
+	case ps2mc.ECCCorrected:
+		c.Corrected++
+	case ps2mc.ECCFailed:
+		return ErrCardECC
+	}
+	return nil
+}
+
+// WritePage writes a 512-byte page, generating its spare area.
+// The page must have been erased first.
+func (c *PS2MemoryCard) WritePage(page uint32, data []byte) error {
+	if page >= c.specs.Pages {
+		return ErrCardFrame
+	}
+	if len(data) < ps2mc.PageSize {
+		return ErrCardBuffer
+	}
+
+	var spare [ps2mc.SpareSize]byte
+	ps2mc.PageECC(data, spare[:])
+
+	if err := c.setAddress(ps2CardCmdWriteAddr, page); err != nil {
+		return err
+	}
+	for i := 0; i < ps2mc.ChunksPerPage; i++ {
+		if err := c.writeData(data[i*ps2mc.ChunkSize : (i+1)*ps2mc.ChunkSize]); err != nil {
+			return err
+		}
+	}
+	if err := c.writeData(spare[:]); err != nil {
+		return err
+	}
+	if err := c.simple(ps2CardCmdWriteEnd); err != nil {
+		return ErrCardWrite
+	}
+	return c.simple(ps2CardCmdReadWriteEnd)
+}
+
+// EraseBlock erases a block of pages (to all 0xFF).
+func (c *PS2MemoryCard) EraseBlock(block uint32) error {
+	if block >= c.specs.Blocks() {
+		return ErrCardFrame
+	}
+
+	if err := c.setAddress(ps2CardCmdEraseAddr, block*uint32(c.specs.PagesPerBlock)); err != nil {
+		return err
+	}
+	if err := c.simple(ps2CardCmdErase); err != nil {
+		return err
+	}
+	if err := c.simple(ps2CardCmdWriteEnd); err != nil {
+		return ErrCardWrite
+	}
+	return c.simple(ps2CardCmdReadWriteEnd)
+}
+
+// setAddress sends a page address command.
+func (c *PS2MemoryCard) setAddress(cmd byte, page uint32) error {
+	msg := c.command(cmd, ps2CardAddrLen)
+	binary.LittleEndian.PutUint32(msg[2:], page)
+	msg[6] = xor(msg[2:6])
+	if err := c.transfer(msg); err != nil {
+		return err
+	}
+	return c.checkStatus(7)
+}
+
+// readData reads the next len(data) bytes (at most 128) from the card.
+func (c *PS2MemoryCard) readData(data []byte) error {
+	n := len(data)
+	msg := c.command(ps2CardCmdReadData, n+6)
+	msg[2] = byte(n)
+	if err := c.transfer(msg); err != nil {
+		return err
+	}
+
+	resp := c.resp[:len(msg)]
+	if resp[3] != ps2CardReply {
+		return ErrCardProtocol
+	}
+	if xor(resp[4:4+n]) != resp[4+n] {
+		return ErrCardChecksum
+	}
+	if resp[5+n] != ps2CardTermReady {
+		return ErrCardBusy
+	}
+	copy(data, resp[4:4+n])
+	return nil
+}
+
+// writeData writes the next len(data) bytes (at most 128) to the card.
+func (c *PS2MemoryCard) writeData(data []byte) error {
+	n := len(data)
+	msg := c.command(ps2CardCmdWriteData, n+6)
+	msg[2] = byte(n)
+	copy(msg[3:], data)
+	msg[3+n] = xor(data)
+	if err := c.transfer(msg); err != nil {
+		return err
+	}
+	return c.checkStatus(4 + n)
+}
+
+// simple sends a command without parameters.
+func (c *PS2MemoryCard) simple(cmd byte) error {
+	if err := c.transfer(c.command(cmd, ps2CardSimpleLen)); err != nil {
+		return err
+	}
+	return c.checkStatus(2)
+}
+
+// command prepares a zeroed frame of length n for cmd.
+func (c *PS2MemoryCard) command(cmd byte, n int) []byte {
+	msg := c.msg[:n]
+	for i := range msg {
+		msg[i] = 0x00
+	}
+	msg[0] = memoryCardAddress
+	msg[1] = cmd
+	return msg
+}
+
+// transfer exchanges a frame with the card.
+func (c *PS2MemoryCard) transfer(msg []byte) error {
+	n := c.g.exchange(c.slot, msg, c.resp[:], memoryCardAckTimeout)
+	if n < 2 {
+		return ErrNoCard
+	}
+	if n < len(msg) {
+		return ErrCardNoResponse
+	}
+	return nil
+}
+
+// checkStatus checks the accept byte at i and the terminator after it.
+func (c *PS2MemoryCard) checkStatus(i int) error {
+	if c.resp[i] != ps2CardReply {
+		return ErrCardProtocol
+	}
+	if c.resp[i+1] != ps2CardTermReady {
+		return ErrCardBusy
+	}
+	return nil
+}
+
+// xor returns the XOR of all bytes in data.
+func xor(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum ^= b
+	}
+	return sum
+}
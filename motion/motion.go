@@ -0,0 +1,341 @@
+// Package motion recognizes fighting-game motions and button sequences.
+//
+// Directions use numpad notation, with the player facing right:
+//
+//	7 8 9
+//	4 5 6
+//	1 2 3
+//
+// A Recognizer keeps a short history of the directions entered and the
+// buttons pressed, each stamped with the time of the poll that saw it,
+// and checks it against a list of Patterns after every poll. Because
+// the driver timestamps the polls, windows and charge times are as
+// accurate as the poll rate allows, however late the application gets
+// round to calling Update.
+package motion
+
+import (
+	"time"
+
+	"gpsx"
+)
+
+// Direction is a stick or D-pad direction in numpad notation.
+type Direction uint8
+
+// Directions, for a player facing right
+const (
+	DownBack    Direction = 1
+	Down        Direction = 2
+	DownForward Direction = 3
+	Back        Direction = 4
+	Neutral     Direction = 5
+	Forward     Direction = 6
+	UpBack      Direction = 7
+	Up          Direction = 8
+	UpForward   Direction = 9
+)
+
+// Step is one input of a Pattern: entering a direction, or pressing
+// buttons. Exactly one of the fields is set.
+type Step struct {
+	Dir   Direction
+	Press gpsx.Buttons
+}
+
+// Pattern is an input to recognize.
+type Pattern struct {
+	Name  string
+	Steps []Step
+
+	// Window is the longest time from the first step to the last. For a
+	// charge pattern it runs from the release of the charge, the input
+	// after the held direction, since the charge itself is longer.
+	Window time.Duration
+
+	// Charge, if set, is how long the direction of the first step must be
+	// held before the next step. Any direction containing it counts, so
+	// a Back charge can be held in DownBack.
+	Charge time.Duration
+
+	// Strict forbids other inputs between the steps; returns to Neutral
+	// are still allowed. Without it other inputs may come in between, so
+	// a rolled motion such as 6 3 2 3 still counts as 6 2 3.
+	Strict bool
+}
+
+// Common motions; add the button to press at the end with Then.
+var (
+	QuarterCircleForward = Pattern{Name: "QCF", Steps: dirs(Down, DownForward, Forward), Window: 300 * time.Millisecond}
+	QuarterCircleBack    = Pattern{Name: "QCB", Steps: dirs(Down, DownBack, Back), Window: 300 * time.Millisecond}
+	DragonPunch          = Pattern{Name: "DP", Steps: dirs(Forward, Down, DownForward), Window: 300 * time.Millisecond}
+	HalfCircleForward    = Pattern{Name: "HCF", Steps: dirs(Back, DownBack, Down, DownForward, Forward), Window: 500 * time.Millisecond}
+	ChargeBackForward    = Pattern{Name: "Charge B,F", Steps: dirs(Back, Forward), Window: 200 * time.Millisecond, Charge: 800 * time.Millisecond}
+	ChargeDownUp         = Pattern{Name: "Charge D,U", Steps: dirs(Down, Up), Window: 200 * time.Millisecond, Charge: 800 * time.Millisecond}
+)
+
+// dirs returns one step per direction.
+func dirs(d ...Direction) []Step {
+	steps := make([]Step, len(d))
+	for i := range d {
+		steps[i].Dir = d[i]
+	}
+	return steps
+}
+
+// Then returns a copy of the pattern that ends with pressing btns, and
+// named name.
+func (p Pattern) Then(name string, btns gpsx.Buttons) Pattern {
+	steps := make([]Step, len(p.Steps), len(p.Steps)+1)
+	copy(steps, p.Steps)
+	p.Steps = append(steps, Step{Press: btns})
+	p.Name = name
+	return p
+}
+
+// historySize is the number of inputs remembered.
+const historySize = 32
+
+// input is one entry of the history.
+type input struct {
+	time  time.Time
+	dir   Direction    // Direction entered, or 0
+	press gpsx.Buttons // Buttons pressed, if dir is 0
+}
+
+// Recognizer matches the inputs of one pad against a list of patterns.
+type Recognizer struct {
+	psx *gpsx.GPSX
+	pad uint8
+
+	patterns  []Pattern
+	facing    bool // Facing left: mirror Back and Forward
+	stick     bool // Read directions from the left stick
+	threshold uint8
+
+	dir     Direction
+	buttons gpsx.Buttons
+
+	history [historySize]input
+	head    int // Index of the oldest input
+	count   int
+}
+
+// New creates a Recognizer for the pad, reading directions from the
+// D-pad. Patterns are checked in the order they are added, so add
+// motions that contain others first (DragonPunch before
+// QuarterCircleForward).
+func New(psx *gpsx.GPSX, pad uint8, patterns ...Pattern) *Recognizer {
+	return &Recognizer{
+		psx:       psx,
+		pad:       pad,
+		patterns:  patterns,
+		dir:       Neutral,
+		threshold: 0x40,
+	}
+}
+
+// Add appends a pattern and returns its index.
+func (r *Recognizer) Add(p Pattern) int {
+	r.patterns = append(r.patterns, p)
+	return len(r.patterns) - 1
+}
+
+// Pattern returns the pattern at index i.
+func (r *Recognizer) Pattern(i int) Pattern {
+	return r.patterns[i]
+}
+
+// SetFacingLeft mirrors Back and Forward for a player facing left.
+func (r *Recognizer) SetFacingLeft(left bool) {
+	r.facing = left
+}
+
+// UseStick reads directions from the left stick instead of the D-pad. A
+// stick axis counts once it is more than threshold away from the centre.
+func (r *Recognizer) UseStick(threshold uint8) {
+	r.stick = true
+	r.threshold = threshold
+}
+
+// Reset forgets the input history.
+func (r *Recognizer) Reset() {
+	r.count = 0
+}
+
+// Update polls the pad and feeds the new state. It returns the index of
+// the pattern completed by this poll, or -1.
+func (r *Recognizer) Update() int {
+	r.psx.UpdateState(r.pad)
+	return r.Feed(r.psx.PollTime(r.pad), r.psx.State(r.pad))
+}
+
+// Feed adds the state of a poll made at t, for code that polls the pad
+// itself. It returns the index of the pattern completed, or -1.
+func (r *Recognizer) Feed(t time.Time, s gpsx.State) int {
+	dir := r.direction(s)
+	pressed := s.Buttons.Without(r.buttons)
+	if !r.stick {
+		// The D-pad is read as directions only
+		pressed = pressed.Without(dpad)
+	}
+	r.buttons = s.Buttons
+
+	match := -1
+	if dir != r.dir {
+		r.dir = dir
+		r.push(input{time: t, dir: dir})
+		match = r.match()
+	}
+	if pressed != 0 {
+		r.push(input{time: t, press: pressed})
+		if m := r.match(); m >= 0 {
+			match = m
+		}
+	}
+	return match
+}
+
+// dpad is the set of D-pad buttons.
+var dpad = gpsx.ButtonsOf(gpsx.ButtonUp, gpsx.ButtonDown, gpsx.ButtonLeft, gpsx.ButtonRight)
+
+// direction reads the direction of a poll.
+func (r *Recognizer) direction(s gpsx.State) Direction {
+	var up, down, left, right bool
+	if r.stick {
+		// In int, so thresholds near 0x80 don't wrap around
+		x, y, t := int(s.LeftX), int(s.LeftY), int(r.threshold)
+		up = y < 0x80-t
+		down = y > 0x80+t
+		left = x < 0x80-t
+		right = x > 0x80+t
+	} else {
+		up = s.IsDown(gpsx.ButtonUp)
+		down = s.IsDown(gpsx.ButtonDown)
+		left = s.IsDown(gpsx.ButtonLeft)
+		right = s.IsDown(gpsx.ButtonRight)
+	}
+	if r.facing {
+		left, right = right, left
+	}
+
+	d := Neutral
+	switch {
+	case up && !down:
+		d += 3
+	case down && !up:
+		d -= 3
+	}
+	switch {
+	case right && !left:
+		d++
+	case left && !right:
+		d--
+	}
+	return d
+}
+
+// push appends an input, dropping the oldest when full.
+func (r *Recognizer) push(in input) {
+	if r.count == historySize {
+		r.head = (r.head + 1) % historySize
+		r.count--
+	}
+	r.history[(r.head+r.count)%historySize] = in
+	r.count++
+}
+
+// at returns the i-th oldest input.
+func (r *Recognizer) at(i int) *input {
+	return &r.history[(r.head+i)%historySize]
+}
+
+// match checks the patterns against the history, which has just grown by
+// one input. A pattern matches only if its last step is that input, so
+// each completion is reported once. The history is cleared on a match.
+func (r *Recognizer) match() int {
+	for i := range r.patterns {
+		if r.matches(&r.patterns[i]) {
+			r.Reset()
+			return i
+		}
+	}
+	return -1
+}
+
+// matches reports whether the history ends with p.
+func (r *Recognizer) matches(p *Pattern) bool {
+	n := len(p.Steps)
+	if n == 0 || !stepMatches(p.Steps[n-1], r.at(r.count-1)) {
+		return false
+	}
+	last := r.at(r.count - 1).time
+
+	step := n - 2
+	first := r.count - 1
+	for i := r.count - 2; i >= 0 && step >= 0; i-- {
+		in := r.at(i)
+		charge := step == 0 && p.Charge > 0
+		if p.Window > 0 && !charge && last.Sub(in.time) > p.Window {
+			break
+		}
+		if stepMatches(p.Steps[step], in) || (charge && contains(in.dir, p.Steps[0].Dir)) {
+			step--
+			first = i
+			continue
+		}
+		if p.Strict && in.dir != Neutral {
+			return false
+		}
+	}
+	if step >= 0 {
+		return false
+	}
+	if p.Charge > 0 {
+		if first+1 >= r.count || (p.Window > 0 && last.Sub(r.at(first+1).time) > p.Window) {
+			return false
+		}
+		return r.charged(first, p.Steps[0].Dir, p.Charge)
+	}
+	return true
+}
+
+// charged reports whether dir was held for at least d up to input i.
+func (r *Recognizer) charged(i int, dir Direction, d time.Duration) bool {
+	if i+1 >= r.count {
+		return false
+	}
+	end := r.at(i + 1).time
+	start := r.at(i).time
+	for j := i - 1; j >= 0 && contains(r.at(j).dir, dir); j-- {
+		start = r.at(j).time
+	}
+	return end.Sub(start) >= d
+}
+
+// stepMatches reports whether an input is the step.
+func stepMatches(s Step, in *input) bool {
+	if s.Dir != 0 {
+		return in.dir == s.Dir
+	}
+	return in.dir == 0 && in.press.All(s.Press)
+}
+
+// contains reports whether direction d includes the cardinal direction c,
+// as DownBack includes Down and Back.
+func contains(d, c Direction) bool {
+	if d == 0 {
+		return false
+	}
+	switch c {
+	case Down:
+		return d <= DownForward
+	case Up:
+		return d >= UpBack
+	case Back:
+		return d%3 == 1
+	case Forward:
+		return d%3 == 0
+	}
+	return d == c
+}
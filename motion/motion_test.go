@@ -0,0 +1,222 @@
+package motion
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+)
+
+// timed is the pad state seen by a poll at ms milliseconds.
+type timed struct {
+	ms   int
+	btns []gpsx.Button
+}
+
+// feed plays the polls and returns the index matched on each.
+func feed(r *Recognizer, polls []timed) []int {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var got []int
+	for _, p := range polls {
+		s := gpsx.State{Buttons: gpsx.ButtonsOf(p.btns...)}
+		got = append(got, r.Feed(start.Add(time.Duration(p.ms)*time.Millisecond), s))
+	}
+	return got
+}
+
+var (
+	left   = gpsx.ButtonLeft
+	right  = gpsx.ButtonRight
+	down   = gpsx.ButtonDown
+	square = gpsx.ButtonSquare
+)
+
+func TestCharge(t *testing.T) {
+	boom := ChargeBackForward.Then("Sonic Boom", gpsx.ButtonsOf(square))
+
+	tests := []struct {
+		name  string
+		polls []timed
+		want  int
+	}{
+		{"charged", []timed{{0, nil}, {100, []gpsx.Button{left}}, {1000, []gpsx.Button{right}}, {1050, []gpsx.Button{right, square}}}, 0},
+		{"charged down-back", []timed{{0, []gpsx.Button{down, left}}, {900, []gpsx.Button{left}}, {950, []gpsx.Button{right}}, {1000, []gpsx.Button{right, square}}}, 0},
+		{"short charge", []timed{{0, nil}, {100, []gpsx.Button{left}}, {600, []gpsx.Button{right}}, {650, []gpsx.Button{right, square}}}, -1},
+		{"late release", []timed{{0, nil}, {100, []gpsx.Button{left}}, {1000, []gpsx.Button{right}}, {1300, []gpsx.Button{right, square}}}, -1},
+	}
+	for _, tt := range tests {
+		r := New(nil, gpsx.Pad1, boom)
+		got := feed(r, tt.polls)
+		if got[len(got)-1] != tt.want {
+			t.Errorf("%s: matched %v, want %d on the last poll", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMotion(t *testing.T) {
+	fireball := QuarterCircleForward.Then("Fireball", gpsx.ButtonsOf(square))
+
+	r := New(nil, gpsx.Pad1, fireball)
+	got := feed(r, []timed{
+		{0, nil}, {16, []gpsx.Button{down}}, {32, []gpsx.Button{down, right}},
+		{48, []gpsx.Button{right}}, {64, []gpsx.Button{right, square}},
+	})
+	if got[4] != 0 {
+		t.Errorf("fireball: matched %v", got)
+	}
+
+	// The same inputs spread over more than the window
+	r = New(nil, gpsx.Pad1, fireball)
+	got = feed(r, []timed{
+		{0, nil}, {16, []gpsx.Button{down}}, {200, []gpsx.Button{down, right}},
+		{300, []gpsx.Button{right}}, {400, []gpsx.Button{right, square}},
+	})
+	if got[4] != -1 {
+		t.Errorf("slow fireball: matched %v", got)
+	}
+}
+
+// stickPoll is the left stick and buttons seen by a poll at ms
+// milliseconds.
+type stickPoll struct {
+	ms   int
+	x, y uint8
+	btns []gpsx.Button
+}
+
+// feedStick plays the polls and returns the index matched on each.
+func feedStick(r *Recognizer, polls []stickPoll) []int {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var got []int
+	for _, p := range polls {
+		s := gpsx.State{Buttons: gpsx.ButtonsOf(p.btns...), LeftX: p.x, LeftY: p.y, RightX: 0x80, RightY: 0x80}
+		got = append(got, r.Feed(start.Add(time.Duration(p.ms)*time.Millisecond), s))
+	}
+	return got
+}
+
+func TestStick(t *testing.T) {
+	fireball := QuarterCircleForward.Then("Fireball", gpsx.ButtonsOf(square))
+	polls := []stickPoll{
+		{0, 0x80, 0x80, nil}, {16, 0x90, 0xFF, nil}, {32, 0xF0, 0xF0, nil},
+		{48, 0xFF, 0x70, nil}, {64, 0xFF, 0x70, []gpsx.Button{square}},
+	}
+
+	tests := []struct {
+		name      string
+		threshold uint8
+		want      int
+	}{
+		{"default", 0x40, 0},
+		{"small", 0x08, -1}, // 0x90 reads as DownForward
+		{"wide", 0x7F, -1},
+		// Thresholds past the edge never count, rather than wrapping
+		// round to count everything
+		{"past the edge", 0xC0, -1},
+	}
+	for _, tt := range tests {
+		r := New(nil, gpsx.Pad1, fireball)
+		r.UseStick(tt.threshold)
+		got := feedStick(r, polls)
+		if got[len(got)-1] != tt.want {
+			t.Errorf("%s: matched %v, want %d on the last poll", tt.name, got, tt.want)
+		}
+	}
+
+	r := New(nil, gpsx.Pad1)
+	r.UseStick(0xC0)
+	for _, s := range []gpsx.State{{LeftX: 0x80, LeftY: 0x80}, {LeftX: 0x00, LeftY: 0xFF}} {
+		if d := r.direction(s); d != Neutral {
+			t.Errorf("threshold 0xC0: stick %02X,%02X reads %d", s.LeftX, s.LeftY, d)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		polls  []timed
+		strict bool
+		want   int
+	}{
+		{"rolled", rolled, false, 0},
+		{"rolled strict", rolled, true, -1},
+		{"neutral between strict", []timed{
+			{0, nil}, {16, []gpsx.Button{down}}, {32, nil},
+			{48, []gpsx.Button{down, right}}, {64, []gpsx.Button{right}}, {80, []gpsx.Button{right, square}},
+		}, true, 0},
+	}
+	for _, tt := range tests {
+		fireball := QuarterCircleForward.Then("Fireball", gpsx.ButtonsOf(square))
+		fireball.Strict = tt.strict
+		r := New(nil, gpsx.Pad1, fireball)
+		got := feed(r, tt.polls)
+		if got[len(got)-1] != tt.want {
+			t.Errorf("%s: matched %v, want %d on the last poll", tt.name, got, tt.want)
+		}
+	}
+}
+
+// rolled is a fireball with a stray DownBack: 2 1 3 6 Square.
+var rolled = []timed{
+	{0, nil}, {16, []gpsx.Button{down}}, {32, []gpsx.Button{down, left}},
+	{48, []gpsx.Button{down, right}}, {64, []gpsx.Button{right}}, {80, []gpsx.Button{right, square}},
+}
+
+func TestButtonSequence(t *testing.T) {
+	var (
+		triangle = gpsx.ButtonTriangle
+		l1       = gpsx.ButtonL1
+		r1       = gpsx.ButtonR1
+	)
+	code := Pattern{
+		Name: "Cheat",
+		Steps: []Step{
+			{Press: triangle.Mask()}, {Press: l1.Mask()}, {Press: triangle.Mask()}, {Press: r1.Mask()},
+		},
+		Window: 2 * time.Second,
+	}
+
+	// press returns polls pressing then releasing btn at ms
+	press := func(ms int, btn gpsx.Button) []timed {
+		return []timed{{ms, []gpsx.Button{btn}}, {ms + 50, nil}}
+	}
+	seq := func(ms int, btns ...gpsx.Button) []timed {
+		polls := []timed{{0, nil}}
+		for i, btn := range btns {
+			polls = append(polls, press(ms*(i+1), btn)...)
+		}
+		return polls
+	}
+
+	tests := []struct {
+		name   string
+		polls  []timed
+		strict bool
+		want   int // Match on the press of the last button
+	}{
+		{"code", seq(200, triangle, l1, triangle, r1), false, 0},
+		{"extra button", seq(200, triangle, l1, square, triangle, r1), false, 0},
+		{"extra button strict", seq(200, triangle, l1, square, triangle, r1), true, -1},
+		{"too slow", seq(700, triangle, l1, triangle, r1), false, -1},
+		// D-pad presses are directions, not steps of the code
+		{"with d-pad", seq(200, triangle, l1, gpsx.ButtonUp, triangle, r1), false, 0},
+	}
+	for _, tt := range tests {
+		p := code
+		p.Strict = tt.strict
+		r := New(nil, gpsx.Pad1, p)
+		got := feed(r, tt.polls)
+		if got[len(got)-2] != tt.want {
+			t.Errorf("%s: matched %v, want %d on the last press", tt.name, got, tt.want)
+		}
+	}
+
+	// Holding the last button does not match again
+	r := New(nil, gpsx.Pad1, code)
+	polls := seq(200, triangle, l1, triangle, r1)
+	polls = append(polls[:len(polls)-1], timed{1000, []gpsx.Button{r1}})
+	if got := feed(r, polls); got[len(got)-1] != -1 {
+		t.Errorf("held button matched again: %v", got)
+	}
+}
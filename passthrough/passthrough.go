@@ -0,0 +1,257 @@
+// Package passthrough sits between a console and a real controller,
+// forwarding polls while remapping buttons, adding turbo and scaling the
+// sticks. Rumble requested by the game is relayed back to the real pad.
+//
+// The real pad is read through gpsx.GPSX and the console is answered by
+// a dualshock.Controller served on a gpsx.Device. The real pad is polled
+// after each console poll, so the console always sees input at most one
+// poll period old.
+package passthrough
+
+import (
+	"time"
+
+	"gpsx"
+	"gpsx/bus"
+	"gpsx/dualshock"
+)
+
+// Console is the console side of the passthrough, normally a gpsx.Device.
+type Console interface {
+	// ServeFrame answers one frame with r, giving up after timeout
+	// (zero waits forever). It returns false on timeout.
+	ServeFrame(r bus.Responder, timeout time.Duration) bool
+}
+
+// passthroughInterval is the pause after each command to the real pad.
+// It must be well below the console's poll period.
+const passthroughInterval = 200 * time.Microsecond
+
+// cmdPoll is the controller poll command.
+const cmdPoll byte = 0x42
+
+// Passthrough forwards a real controller to a console.
+type Passthrough struct {
+	psx  *gpsx.GPSX
+	pad  uint8
+	dev  Console
+	ctrl *dualshock.Controller
+	poll pollWatch
+
+	// mapping[i] is the bit of the button reported for button bit i
+	mapping [16]uint8
+
+	turbo      gpsx.Buttons // Turbo buttons (after remapping)
+	turboRate  uint8        // Console polls per turbo half period
+	turboCount uint8
+
+	leftScale  int // Stick scale in percent
+	rightScale int
+}
+
+// New creates a Passthrough reading the real controller on pad and
+// answering the console on dev. The real pad is put in analog mode with
+// its motors enabled, and its command interval is shortened so a poll
+// fits between two console frames.
+func New(psx *gpsx.GPSX, pad uint8, dev Console) *Passthrough {
+	p := &Passthrough{
+		psx:        psx,
+		pad:        pad,
+		dev:        dev,
+		ctrl:       dualshock.New(),
+		turboRate:  2,
+		leftScale:  100,
+		rightScale: 100,
+	}
+	p.poll.r = p.ctrl
+	for i := range p.mapping {
+		p.mapping[i] = uint8(i)
+	}
+
+	psx.SetCommandInterval(passthroughInterval)
+	psx.Mode(pad, gpsx.ModeAnalog, gpsx.ModeLock)
+	psx.MotorEnable(pad, gpsx.Motor1Enable, gpsx.Motor2Enable)
+	psx.UpdateState(pad)
+	return p
+}
+
+// Remap reports presses of button from as button to.
+// Several buttons may be mapped to the same target. It returns false,
+// leaving the mapping unchanged, if either is not a digital button.
+func (p *Passthrough) Remap(from gpsx.Button, to gpsx.Button) bool {
+	i, j := buttonIndex(from), buttonIndex(to)
+	if i < 0 || j < 0 {
+		return false
+	}
+	p.mapping[i] = uint8(j)
+	return true
+}
+
+// ResetMapping restores the identity mapping.
+func (p *Passthrough) ResetMapping() {
+	for i := range p.mapping {
+		p.mapping[i] = uint8(i)
+	}
+}
+
+// SetTurbo enables or disables turbo on a reported button. It returns
+// false if btn is not a digital button.
+func (p *Passthrough) SetTurbo(btn gpsx.Button, on bool) bool {
+	if buttonIndex(btn) < 0 {
+		return false
+	}
+	if on {
+		p.turbo |= btn.Mask()
+	} else {
+		p.turbo &^= btn.Mask()
+	}
+	return true
+}
+
+// SetTurboRate sets how many console polls a turbo button stays
+// pressed, then released. The default is 2.
+func (p *Passthrough) SetTurboRate(frames uint8) {
+	if frames == 0 {
+		frames = 1
+	}
+	p.turboRate = frames
+}
+
+// SetStickScale scales the stick deflection from the centre, in percent.
+// Results beyond the stick range are clamped.
+func (p *Passthrough) SetStickScale(left int, right int) {
+	p.leftScale = left
+	p.rightScale = right
+}
+
+// Controller returns the controller presented to the console, to read
+// the mode and rumble the game requested.
+func (p *Passthrough) Controller() *dualshock.Controller {
+	return p.ctrl
+}
+
+// Run forwards frames forever.
+func (p *Passthrough) Run() {
+	for {
+		p.Step()
+	}
+}
+
+// Step answers one console frame with the state prepared from the last
+// poll. If the frame was a controller poll, it then relays rumble to the
+// real pad and polls it for the next frame. Other frames on the bus, such
+// as memory card accesses, leave the real pad alone. It returns whether
+// the frame was a controller poll.
+func (p *Passthrough) Step() bool {
+	if !p.dev.ServeFrame(&p.poll, 0) || !p.poll.polled() {
+		return false
+	}
+
+	small, large := p.ctrl.Motors()
+	motor1 := gpsx.Motor1Off
+	if small {
+		motor1 = gpsx.Motor1On
+	}
+	p.psx.Motor(p.pad, motor1, large)
+
+	p.psx.UpdateState(p.pad)
+	p.prepare()
+	return true
+}
+
+// prepare builds the reported state from the last poll of the real pad.
+func (p *Passthrough) prepare() {
+	var down gpsx.Buttons
+	gpsx.AllButtons.Each(func(btn gpsx.Button) {
+		if p.psx.IsDown(p.pad, btn) {
+			down |= 1 << p.mapping[buttonIndex(btn)]
+		}
+	})
+
+	// Turbo buttons are released every other half period
+	p.turboCount++
+	if p.turboCount >= 2*p.turboRate {
+		p.turboCount = 0
+	}
+	if p.turboCount >= p.turboRate {
+		down &^= p.turbo
+	}
+
+	gpsx.AllButtons.Each(func(btn gpsx.Button) {
+		p.ctrl.SetButton(btn, down.Has(btn))
+	})
+
+	p.ctrl.SetSticks(
+		scale(p.psx.AnalogRightX(p.pad), p.rightScale),
+		scale(p.psx.AnalogRightY(p.pad), p.rightScale),
+		scale(p.psx.AnalogLeftX(p.pad), p.leftScale),
+		scale(p.psx.AnalogLeftY(p.pad), p.leftScale),
+	)
+}
+
+// scale scales a stick value around the centre.
+func scale(v uint8, percent int) uint8 {
+	s := 0x80 + (int(v)-0x80)*percent/100
+	if s < 0 {
+		return 0
+	}
+	if s > 0xFF {
+		return 0xFF
+	}
+	return uint8(s)
+}
+
+// buttonIndex returns the bit of a button in gpsx.Buttons, or -1 if btn
+// is not a digital button.
+func buttonIndex(btn gpsx.Button) int {
+	m := btn.Mask()
+	if m.Count() != 1 {
+		return -1
+	}
+	i := 0
+	for ; m > 1; m >>= 1 {
+		i++
+	}
+	return i
+}
+
+// pollWatch passes a frame to r and notes whether it was a controller
+// poll that r answered.
+type pollWatch struct {
+	r    bus.Responder
+	pos  int
+	addr byte
+	cmd  byte
+	ack  bool // Command byte acknowledged
+}
+
+// Select starts a new frame.
+func (w *pollWatch) Select() {
+	w.pos = 0
+	w.addr, w.cmd, w.ack = 0, 0, false
+	w.r.Select()
+}
+
+// Exchange passes a byte to r, noting the address and command.
+func (w *pollWatch) Exchange(cmd byte) (byte, bool) {
+	reply, ack := w.r.Exchange(cmd)
+	switch w.pos {
+	case 0:
+		w.addr = cmd
+	case 1:
+		w.cmd = cmd
+		w.ack = ack
+	}
+	w.pos++
+	return reply, ack
+}
+
+// Deselect ends the frame.
+func (w *pollWatch) Deselect() {
+	w.r.Deselect()
+}
+
+// polled reports whether the last frame was a poll answered by r.
+func (w *pollWatch) polled() bool {
+	return w.addr == bus.AddressController && w.cmd == cmdPoll && w.ack
+}
@@ -0,0 +1,119 @@
+package passthrough
+
+import (
+	"testing"
+	"time"
+
+	"gpsx"
+	"gpsx/bus"
+	"gpsx/sim"
+)
+
+// console plays queued frames to the passthrough like a console would.
+type console struct {
+	frames [][]byte
+	resp   []byte
+}
+
+func (c *console) ServeFrame(r bus.Responder, timeout time.Duration) bool {
+	if len(c.frames) == 0 {
+		return false
+	}
+	msg := c.frames[0]
+	c.frames = c.frames[1:]
+	c.resp = make([]byte, len(msg))
+	c.resp = c.resp[:bus.NewHost(r).Transfer(msg, c.resp)]
+	return true
+}
+
+var (
+	pollFrame = []byte{0x01, 0x42, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	cardFrame = []byte{0x81, 0x52, 0x00, 0x00, 0x00, 0x00}
+)
+
+func newTest(t *testing.T) (*sim.Sim, *console, *Passthrough) {
+	s := sim.New(gpsx.PS2)
+	c := &console{}
+	p := New(s.PSX, gpsx.Pad1, c)
+	return s, c, p
+}
+
+// reported returns the buttons held in the last reply to the console.
+func (c *console) reported() gpsx.Buttons {
+	return gpsx.ParseButtons(c.resp)
+}
+
+func TestPollRelaysPad(t *testing.T) {
+	s, c, p := newTest(t)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.frames = [][]byte{pollFrame, pollFrame}
+	if !p.Step() {
+		t.Fatal("poll frame not seen as a poll")
+	}
+	p.Step()
+	if got := c.reported(); got != gpsx.ButtonsOf(gpsx.ButtonCross) {
+		t.Errorf("reported %v, want Cross", got)
+	}
+}
+
+func TestOtherFramesLeavePadAlone(t *testing.T) {
+	s, c, p := newTest(t)
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.frames = [][]byte{cardFrame, cardFrame, pollFrame}
+	if p.Step() || p.Step() {
+		t.Fatal("memory card frame seen as a poll")
+	}
+	if p.Step(); c.reported() != 0 {
+		t.Errorf("reported %v before the pad was polled", c.reported())
+	}
+	if p.Step() {
+		t.Error("timeout seen as a poll")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	s, c, p := newTest(t)
+
+	if !p.Remap(gpsx.ButtonCross, gpsx.ButtonCircle) {
+		t.Fatal("Remap rejected digital buttons")
+	}
+	if p.Remap(gpsx.Button{}, gpsx.ButtonCross) || p.Remap(gpsx.ButtonCross, gpsx.Button{}) {
+		t.Error("Remap accepted an unknown button")
+	}
+	if p.SetTurbo(gpsx.Button{}, true) {
+		t.Error("SetTurbo accepted an unknown button")
+	}
+
+	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+	c.frames = [][]byte{pollFrame, pollFrame}
+	p.Step()
+	p.Step()
+	if got := c.reported(); got != gpsx.ButtonsOf(gpsx.ButtonCircle) {
+		t.Errorf("reported %v, want Circle", got)
+	}
+}
+
+func TestTurboCountsPolls(t *testing.T) {
+	s, c, p := newTest(t)
+	p.SetTurbo(gpsx.ButtonSquare, true)
+	p.SetTurboRate(1)
+	s.Press(gpsx.Pad1, gpsx.ButtonSquare)
+
+	// Memory card frames between polls must not advance turbo. The first
+	// reply carries the state from before the press.
+	var got []bool
+	for i := 0; i < 4; i++ {
+		c.frames = [][]byte{pollFrame, cardFrame}
+		p.Step()
+		got = append(got, c.reported().Has(gpsx.ButtonSquare))
+		p.Step()
+	}
+	want := []bool{false, false, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Square held on polls %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+//go:build tinygo
+
+package gpsx
+
+import (
+	"machine"
+	"time"
+)
+
+// PinConfig holds the pin configuration for the PS2 controller interface.
+type PinConfig struct {
+	DAT machine.Pin // Data input (requires pull-up)
+	CMD machine.Pin // Command output
+	CLK machine.Pin // Clock output
+	AT1 machine.Pin // Attention for PAD1
+	AT2 machine.Pin // Attention for PAD2 (optional if using only PAD1)
+	ACK machine.Pin // ACK input (optional, recommended for memory cards)
+}
+
+// pinTransport bit-bangs the bus on GPIO pins.
+type pinTransport struct {
+	pins PinConfig
+
+	// Timing configuration
+	waitAfterATT    time.Duration
+	clkHalfCycle    time.Duration
+	ackWaitDuration time.Duration
+}
+
+// New creates a new GPSX controller with the specified platform type and pins.
+func New(psxType uint8, pins PinConfig) *GPSX {
+	t := &pinTransport{pins: pins}
+	g := NewWithTransport(psxType, t)
+
+	// Set timing based on platform
+	if psxType == PS1 {
+		t.waitAfterATT = 50 * time.Microsecond
+		g.commandInterval = 16 * time.Millisecond
+		t.clkHalfCycle = 2 * time.Microsecond
+		t.ackWaitDuration = 15 * time.Microsecond
+	} else { // PS2
+		t.waitAfterATT = 15 * time.Microsecond
+		g.commandInterval = 10 * time.Millisecond
+		t.clkHalfCycle = 1 * time.Microsecond
+		t.ackWaitDuration = 15 * time.Microsecond
+	}
+
+	t.init()
+	return g
+}
+
+// init configures the GPIO pins and sets their initial states.
+func (t *pinTransport) init() {
+	// Configure pins
+	t.pins.CLK.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	t.pins.CMD.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	t.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	t.pins.AT1.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	// Configure AT2 only if it's set (non-zero)
+	if t.pins.AT2 != 0 {
+		t.pins.AT2.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	}
+
+	// Configure ACK only if it's set (non-zero)
+	if t.pins.ACK != 0 {
+		t.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
+	}
+
+	// Set initial pin states
+	t.pins.CLK.High()
+	t.pins.CMD.Low()
+	t.pins.AT1.High()
+	if t.pins.AT2 != 0 {
+		t.pins.AT2.High()
+	}
+}
+
+// attention returns the attention pin for the pad number.
+func (t *pinTransport) attention(pad uint8) machine.Pin {
+	if pad == Pad2 && t.pins.AT2 != 0 {
+		return t.pins.AT2
+	}
+	return t.pins.AT1
+}
+
+// Begin gets attention (pull low).
+func (t *pinTransport) Begin(pad uint8) {
+	t.attention(pad).Low()
+	time.Sleep(t.waitAfterATT)
+}
+
+// End releases attention (pull high).
+func (t *pinTransport) End(pad uint8) {
+	t.attention(pad).High()
+}
+
+// Transfer performs bit-banging SPI transfer of one byte.
+// This implements the PS2 controller communication protocol.
+func (t *pinTransport) Transfer(cmdByte byte) byte {
+	var received byte = 0
+
+	for i := 0; i < 8; i++ {
+		// Set CMD pin based on LSB
+		if cmdByte&0x01 != 0 {
+			t.pins.CMD.High()
+		} else {
+			t.pins.CMD.Low()
+		}
+		cmdByte >>= 1
+
+		// Clock down
+		t.pins.CLK.Low()
+		time.Sleep(t.clkHalfCycle)
+
+		// Clock up
+		t.pins.CLK.High()
+		time.Sleep(t.clkHalfCycle)
+
+		// Read DAT pin on rising edge (MSB-first reception)
+		received >>= 1
+		if t.pins.DAT.Get() {
+			received |= 0x80
+		}
+	}
+
+	return received
+}
+
+// WaitAck waits for the device to pulse ACK after a byte.
+// Without an ACK pin it waits a fixed time and assumes the pulse arrived.
+func (t *pinTransport) WaitAck(timeout time.Duration) bool {
+	if t.pins.ACK == 0 {
+		time.Sleep(t.ackWaitDuration)
+		return true
+	}
+
+	deadline := time.Now().Add(timeout)
+	for t.pins.ACK.Get() {
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+	for !t.pins.ACK.Get() {
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+	return true
+}
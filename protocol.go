@@ -2,49 +2,14 @@ package gpsx
 
 import "time"
 
-// readWriteByte performs bit-banging SPI transfer of one byte.
-// This implements the PS2 controller communication protocol.
-func (g *GPSX) readWriteByte(cmdByte byte) byte {
-	var received byte = 0
-
-	for i := 0; i < 8; i++ {
-		// Set CMD pin based on LSB
-		if cmdByte&0x01 != 0 {
-			g.pins.CMD.High()
-		} else {
-			g.pins.CMD.Low()
-		}
-		cmdByte >>= 1
-
-		// Clock down
-		g.pins.CLK.Low()
-		time.Sleep(g.clkHalfCycle)
-
-		// Clock up
-		g.pins.CLK.High()
-		time.Sleep(g.clkHalfCycle)
-
-		// Read DAT pin on rising edge (MSB-first reception)
-		received >>= 1
-		if g.pins.DAT.Get() {
-			received |= 0x80
-		}
-	}
-
-	return received
-}
+// commandAckTimeout bounds the wait for ACK after each byte of a pad
+// command. Pads that do not pulse ACK just cost this much per byte.
+const commandAckTimeout = 15 * time.Microsecond
 
 // sendCommand sends a command sequence to the specified pad and stores the response.
 func (g *GPSX) sendCommand(pad uint8, msg []byte) {
-	// Select attention pin based on pad number
-	attPin := g.pins.AT1
-	if pad == Pad2 && g.pins.AT2 != 0 {
-		attPin = g.pins.AT2
-	}
-
-	// Get attention (pull low)
-	attPin.Low()
-	time.Sleep(g.waitAfterATT)
+	// Get attention
+	g.bus.Begin(pad)
 
 	// Send/receive 9 bytes
 	for i := 0; i < 9; i++ {
@@ -52,11 +17,32 @@ func (g *GPSX) sendCommand(pad uint8, msg []byte) {
 		if i < len(msg) {
 			cmd = msg[i]
 		}
-		g.padState[i] = g.readWriteByte(cmd)
-		time.Sleep(g.ackWaitDuration)
+		g.padState[i] = g.bus.Transfer(cmd)
+		g.bus.WaitAck(commandAckTimeout)
+	}
+
+	// Release attention
+	g.bus.End(pad)
+	time.Sleep(g.commandInterval)
+}
+
+// exchange sends msg to the device on the pad slot and stores the reply in
+// resp, which must be at least as long as msg. Unlike sendCommand it waits
+// for ACK after every byte but the last, and stops early if the device
+// does not acknowledge. It returns the number of bytes exchanged.
+func (g *GPSX) exchange(pad uint8, msg []byte, resp []byte, ackTimeout time.Duration) int {
+	g.bus.Begin(pad)
+
+	n := 0
+	for n < len(msg) {
+		resp[n] = g.bus.Transfer(msg[n])
+		n++
+		if n < len(msg) && !g.bus.WaitAck(ackTimeout) {
+			break
+		}
 	}
 
-	// Release attention (pull high)
-	attPin.High()
+	g.bus.End(pad)
 	time.Sleep(g.commandInterval)
+	return n
 }
@@ -0,0 +1,230 @@
+package ps1mc
+
+import "encoding/binary"
+
+// Block allocation states
+const (
+	stateFirst       byte = 0x51 // First block of a save
+	stateMiddle      byte = 0x52 // Middle block of a save
+	stateLast        byte = 0x53 // Last block of a save
+	stateFree        byte = 0xA0 // Free block
+	stateDeleteFirst byte = 0xA1 // Deleted first block
+	stateDeleteMid   byte = 0xA2 // Deleted middle block
+	stateDeleteLast  byte = 0xA3 // Deleted last block
+)
+
+// Directory frame layout
+const (
+	entrySize    = 0x04
+	entryNext    = 0x08
+	entryName    = 0x0A
+	entryNameLen = 20
+
+	noNext uint16 = 0xFFFF
+)
+
+// Save describes a save on the card.
+type Save struct {
+	Name   string // File name, e.g. "BASCUS-94163FF7"
+	Size   uint32 // Size in bytes, a multiple of BlockSize
+	Blocks []int  // Blocks holding the save, in order (1-15)
+}
+
+// Free returns true if the directory marks the block as free.
+// Deleted blocks count as free.
+func (img *Image) Free(block int) bool {
+	state := img.Frame(uint16(block))[0]
+	return state == stateFree || state&0xF0 == stateFree
+}
+
+// FreeBlocks returns the number of free save blocks.
+func (img *Image) FreeBlocks() int {
+	n := 0
+	for b := 1; b <= SaveBlocks; b++ {
+		if img.Free(b) {
+			n++
+		}
+	}
+	return n
+}
+
+// Saves returns the saves on the card in directory order.
+func (img *Image) Saves() ([]Save, error) {
+	if !img.Formatted() {
+		return nil, ErrNotFormatted
+	}
+
+	var saves []Save
+	for b := 1; b <= SaveBlocks; b++ {
+		if img.Frame(uint16(b))[0] != stateFirst {
+			continue
+		}
+		s, err := img.save(b)
+		if err != nil {
+			return nil, err
+		}
+		saves = append(saves, s)
+	}
+	return saves, nil
+}
+
+// Find returns the save with the given name.
+func (img *Image) Find(name string) (Save, error) {
+	saves, err := img.Saves()
+	if err != nil {
+		return Save{}, err
+	}
+	for _, s := range saves {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Save{}, ErrNotFound
+}
+
+// Check validates every block chain and directory checksum on the card.
+func (img *Image) Check() error {
+	if !img.Formatted() {
+		return ErrNotFormatted
+	}
+
+	// Every in-use block must be reached from exactly one first block
+	var used [Blocks]bool
+	for b := 1; b <= SaveBlocks; b++ {
+		e := img.Frame(uint16(b))
+		if checksum(e) != e[FrameSize-1] {
+			return ErrBrokenChain
+		}
+		if e[0] != stateFirst {
+			continue
+		}
+		s, err := img.save(b)
+		if err != nil {
+			return err
+		}
+		for _, sb := range s.Blocks {
+			if used[sb] {
+				return ErrBrokenChain
+			}
+			used[sb] = true
+		}
+	}
+	for b := 1; b <= SaveBlocks; b++ {
+		state := img.Frame(uint16(b))[0]
+		if (state == stateMiddle || state == stateLast) && !used[b] {
+			return ErrBrokenChain
+		}
+	}
+	return nil
+}
+
+// save follows the block chain of the save starting at block first.
+func (img *Image) save(first int) (Save, error) {
+	e := img.Frame(uint16(first))
+	s := Save{
+		Name: readName(e),
+		Size: binary.LittleEndian.Uint32(e[entrySize:]),
+	}
+
+	b := first
+	for {
+		s.Blocks = append(s.Blocks, b)
+		if len(s.Blocks) > SaveBlocks {
+			return Save{}, ErrBrokenChain
+		}
+
+		next := binary.LittleEndian.Uint16(img.Frame(uint16(b))[entryNext:])
+		if next == noNext {
+			break
+		}
+		if next >= SaveBlocks {
+			return Save{}, ErrBrokenChain
+		}
+		b = int(next) + 1
+
+		state := img.Frame(uint16(b))[0]
+		if state != stateMiddle && state != stateLast {
+			return Save{}, ErrBrokenChain
+		}
+	}
+
+	if img.Frame(uint16(b))[0] != stateLast && len(s.Blocks) > 1 {
+		return Save{}, ErrBrokenChain
+	}
+	if s.Size != uint32(len(s.Blocks))*BlockSize {
+		return Save{}, ErrBrokenChain
+	}
+	return s, nil
+}
+
+// allocate returns n free blocks, lowest first.
+func (img *Image) allocate(n int) ([]int, error) {
+	var blocks []int
+	for b := 1; b <= SaveBlocks && len(blocks) < n; b++ {
+		if img.Free(b) {
+			blocks = append(blocks, b)
+		}
+	}
+	if len(blocks) < n {
+		return nil, ErrCardFull
+	}
+	return blocks, nil
+}
+
+// link writes the directory frames chaining blocks into one save.
+func (img *Image) link(name string, blocks []int) {
+	size := uint32(len(blocks)) * BlockSize
+	for i, b := range blocks {
+		state := stateMiddle
+		switch {
+		case i == 0:
+			state = stateFirst
+		case i == len(blocks)-1:
+			state = stateLast
+		}
+
+		next := noNext
+		if i+1 < len(blocks) {
+			next = uint16(blocks[i+1] - 1)
+		}
+
+		if i == 0 {
+			writeEntry(img.Frame(uint16(b)), state, size, next, name)
+		} else {
+			writeEntry(img.Frame(uint16(b)), state, 0, next, "")
+		}
+	}
+}
+
+// Delete marks the blocks of a save as free.
+func (img *Image) Delete(name string) error {
+	s, err := img.Find(name)
+	if err != nil {
+		return err
+	}
+	for _, b := range s.Blocks {
+		writeEntry(img.Frame(uint16(b)), stateFree, 0, noNext, "")
+	}
+	return nil
+}
+
+// writeEntry fills a directory frame.
+func writeEntry(e []byte, state byte, size uint32, next uint16, name string) {
+	clear(e[:FrameSize])
+	e[0] = state
+	binary.LittleEndian.PutUint32(e[entrySize:], size)
+	binary.LittleEndian.PutUint16(e[entryNext:], next)
+	copy(e[entryName:entryName+entryNameLen], name)
+	e[FrameSize-1] = checksum(e)
+}
+
+// readName returns the zero-terminated file name of a directory frame.
+func readName(e []byte) string {
+	name := e[entryName : entryName+entryNameLen]
+	for i, c := range name {
+		if c == 0 {
+			return string(name[:i])
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,211 @@
+package ps1mc
+
+import "gpsx/bus"
+
+// Memory card commands
+const (
+	cmdRead  byte = 0x52 // 'R'
+	cmdWrite byte = 0x57 // 'W'
+	cmdID    byte = 0x53 // 'S'
+)
+
+// Memory card replies
+const (
+	replyID1       byte = 0x5A
+	replyID2       byte = 0x5D
+	replyCmdAck1   byte = 0x5C
+	replyCmdAck2   byte = 0x5D
+	replyGood      byte = 0x47 // 'G'
+	replyBadSum    byte = 0x4E // 'N'
+	replyBadSector byte = 0xFF
+
+	// FLAG bit set until the first write after power on
+	flagFresh byte = 0x08
+)
+
+// idReply is the reply to the Get ID command after the two ID bytes.
+var idReply = [...]byte{replyCmdAck1, replyCmdAck2, 0x04, 0x00, 0x00, 0x80}
+
+// Emulator answers memory card commands from a console, serving frames
+// from a Device such as an *Image. It implements bus.Responder.
+type Emulator struct {
+	dev  Device
+	flag byte
+
+	// Frame in progress
+	pos     int  // Index of the next byte received
+	cmd     byte // Command byte
+	silent  bool // Not addressed, or transfer aborted
+	msb     byte
+	lsb     byte
+	sum     byte
+	status  byte
+	frame   [FrameSize]byte
+	invalid bool // Address out of range or unreadable
+}
+
+// NewEmulator creates an Emulator serving the frames of dev.
+func NewEmulator(dev Device) *Emulator {
+	return &Emulator{
+		dev:  dev,
+		flag: flagFresh,
+	}
+}
+
+// Select starts a new frame.
+func (e *Emulator) Select() {
+	e.pos = 0
+	e.silent = false
+}
+
+// Deselect ends the frame.
+func (e *Emulator) Deselect() {
+	e.silent = true
+}
+
+// Exchange receives a byte from the console and prepares the next reply.
+func (e *Emulator) Exchange(cmd byte) (byte, bool) {
+	if e.silent {
+		return bus.HiZ, false
+	}
+
+	p := e.pos
+	e.pos++
+
+	switch p {
+	case 0:
+		if cmd != bus.AddressMemoryCard {
+			return e.stop()
+		}
+		return e.flag, true
+	case 1:
+		if cmd != cmdRead && cmd != cmdWrite && cmd != cmdID {
+			return e.stop()
+		}
+		e.cmd = cmd
+		return replyID1, true
+	case 2:
+		return replyID2, true
+	}
+
+	switch e.cmd {
+	case cmdRead:
+		return e.read(p, cmd)
+	case cmdWrite:
+		return e.write(p, cmd)
+	default:
+		return e.id(p)
+	}
+}
+
+// read handles byte p of a read command:
+//
+//	81 52 00 00 MSB LSB 00 00 00 00 [128 x 00] 00 00
+//	-- FL 5A 5D  00 MSB 5C 5D MSB LSB [data]   CHK 47
+func (e *Emulator) read(p int, cmd byte) (byte, bool) {
+	switch {
+	case p == 3:
+		return 0x00, true
+	case p == 4:
+		e.msb = cmd
+		return cmd, true
+	case p == 5:
+		e.lsb = cmd
+		e.load()
+		return replyCmdAck1, true
+	case p == 6:
+		return replyCmdAck2, true
+	case p == 7:
+		if e.invalid {
+			return 0xFF, true
+		}
+		return e.msb, true
+	case p == 8:
+		if e.invalid {
+			// Abort after the invalid address
+			e.silent = true
+			return 0xFF, true
+		}
+		return e.lsb, true
+	case p < 9+FrameSize:
+		return e.frame[p-9], true
+	case p == 9+FrameSize:
+		return e.sum, true
+	case p == 10+FrameSize:
+		return replyGood, true
+	default:
+		return e.stop()
+	}
+}
+
+// write handles byte p of a write command:
+//
+//	81 57 00 00 MSB LSB [data] CHK 00 00 00
+//	-- FL 5A 5D  00 MSB [pre]  pre 5C 5D 47
+func (e *Emulator) write(p int, cmd byte) (byte, bool) {
+	switch {
+	case p == 3:
+		return 0x00, true
+	case p == 4:
+		e.msb = cmd
+		e.sum = cmd
+		return cmd, true
+	case p == 5:
+		e.lsb = cmd
+		e.sum ^= cmd
+		return cmd, true
+	case p < 6+FrameSize:
+		e.frame[p-6] = cmd
+		e.sum ^= cmd
+		return cmd, true
+	case p == 6+FrameSize:
+		e.store(cmd)
+		return replyCmdAck1, true
+	case p == 7+FrameSize:
+		return replyCmdAck2, true
+	case p == 8+FrameSize:
+		return e.status, true
+	default:
+		return e.stop()
+	}
+}
+
+// id handles byte p of a Get ID command.
+func (e *Emulator) id(p int) (byte, bool) {
+	i := p - 3
+	if i < len(idReply) {
+		return idReply[i], true
+	}
+	return e.stop()
+}
+
+// load reads the addressed frame and its checksum.
+func (e *Emulator) load() {
+	addr := uint16(e.msb)<<8 | uint16(e.lsb)
+	e.invalid = addr >= Frames || e.dev.ReadFrame(addr, e.frame[:]) != nil
+
+	e.sum = e.msb ^ e.lsb
+	for _, b := range e.frame {
+		e.sum ^= b
+	}
+}
+
+// store checks the received checksum and writes the frame.
+func (e *Emulator) store(chk byte) {
+	addr := uint16(e.msb)<<8 | uint16(e.lsb)
+	switch {
+	case chk != e.sum:
+		e.status = replyBadSum
+	case addr >= Frames || e.dev.WriteFrame(addr, e.frame[:]) != nil:
+		e.status = replyBadSector
+	default:
+		e.status = replyGood
+		e.flag &^= flagFresh
+	}
+}
+
+// stop ends the reply for this frame.
+func (e *Emulator) stop() (byte, bool) {
+	e.silent = true
+	return bus.HiZ, false
+}
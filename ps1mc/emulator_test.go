@@ -0,0 +1,144 @@
+package ps1mc
+
+import (
+	"bytes"
+	"testing"
+
+	"gpsx/bus"
+)
+
+// readMsg builds a read command for frame.
+func readMsg(frame uint16) []byte {
+	msg := make([]byte, 10+FrameSize+2)
+	msg[0] = bus.AddressMemoryCard
+	msg[1] = cmdRead
+	msg[4] = byte(frame >> 8)
+	msg[5] = byte(frame)
+	return msg
+}
+
+// writeMsg builds a write command for frame with the given checksum.
+func writeMsg(frame uint16, data []byte, chk byte) []byte {
+	msg := make([]byte, 6+FrameSize+4)
+	msg[0] = bus.AddressMemoryCard
+	msg[1] = cmdWrite
+	msg[4] = byte(frame >> 8)
+	msg[5] = byte(frame)
+	copy(msg[6:], data)
+	msg[6+FrameSize] = chk
+	return msg
+}
+
+func frameSum(frame uint16, data []byte) byte {
+	sum := byte(frame>>8) ^ byte(frame)
+	for _, b := range data {
+		sum ^= b
+	}
+	return sum
+}
+
+func pattern(seed byte) []byte {
+	data := make([]byte, FrameSize)
+	for i := range data {
+		data[i] = seed + byte(i)
+	}
+	return data
+}
+
+func TestEmulatorRead(t *testing.T) {
+	img := new(Image)
+	want := pattern(0x30)
+	copy(img.Frame(0x123), want)
+	host := bus.NewHost(NewEmulator(img))
+
+	msg := readMsg(0x123)
+	resp := make([]byte, len(msg))
+	if n := host.Transfer(msg, resp); n != len(msg) {
+		t.Fatalf("transferred %d bytes, want %d", n, len(msg))
+	}
+
+	head := []byte{bus.HiZ, flagFresh, replyID1, replyID2, 0x00, 0x01, replyCmdAck1, replyCmdAck2, 0x01, 0x23}
+	if !bytes.Equal(resp[:10], head) {
+		t.Errorf("header % X, want % X", resp[:10], head)
+	}
+	if !bytes.Equal(resp[10:10+FrameSize], want) {
+		t.Errorf("data % X, want % X", resp[10:10+FrameSize], want)
+	}
+	if got, sum := resp[10+FrameSize], frameSum(0x123, want); got != sum {
+		t.Errorf("checksum %02X, want %02X", got, sum)
+	}
+	if got := resp[11+FrameSize]; got != replyGood {
+		t.Errorf("end byte %02X, want %02X", got, replyGood)
+	}
+}
+
+func TestEmulatorReadInvalid(t *testing.T) {
+	host := bus.NewHost(NewEmulator(new(Image)))
+
+	msg := readMsg(Frames)
+	resp := make([]byte, len(msg))
+	if n := host.Transfer(msg, resp); n != 10 {
+		t.Fatalf("transferred %d bytes, want 10", n)
+	}
+	if resp[8] != 0xFF || resp[9] != 0xFF {
+		t.Errorf("address echo %02X %02X, want FF FF", resp[8], resp[9])
+	}
+}
+
+func TestEmulatorWrite(t *testing.T) {
+	img := new(Image)
+	emu := NewEmulator(img)
+	host := bus.NewHost(emu)
+
+	data := pattern(0x80)
+	msg := writeMsg(0x040, data, frameSum(0x040, data))
+	resp := make([]byte, len(msg))
+	if n := host.Transfer(msg, resp); n != len(msg) {
+		t.Fatalf("transferred %d bytes, want %d", n, len(msg))
+	}
+	if got := resp[len(msg)-1]; got != replyGood {
+		t.Errorf("end byte %02X, want %02X", got, replyGood)
+	}
+	if !bytes.Equal(img.Frame(0x040), data) {
+		t.Errorf("frame not written")
+	}
+
+	// The fresh flag clears after the first write
+	msg = readMsg(0x040)
+	resp = make([]byte, len(msg))
+	host.Transfer(msg, resp)
+	if resp[1] != 0x00 {
+		t.Errorf("flag %02X after write, want 00", resp[1])
+	}
+	if !bytes.Equal(resp[10:10+FrameSize], data) {
+		t.Errorf("read back % X, want % X", resp[10:10+FrameSize], data)
+	}
+}
+
+func TestEmulatorWriteBadChecksum(t *testing.T) {
+	img := new(Image)
+	host := bus.NewHost(NewEmulator(img))
+
+	data := pattern(0x11)
+	msg := writeMsg(0x200, data, frameSum(0x200, data)^0x01)
+	resp := make([]byte, len(msg))
+	if n := host.Transfer(msg, resp); n != len(msg) {
+		t.Fatalf("transferred %d bytes, want %d", n, len(msg))
+	}
+	if got := resp[len(msg)-1]; got != replyBadSum {
+		t.Errorf("end byte %02X, want %02X", got, replyBadSum)
+	}
+	if !bytes.Equal(img.Frame(0x200), make([]byte, FrameSize)) {
+		t.Errorf("frame written despite bad checksum")
+	}
+}
+
+func TestEmulatorOtherAddress(t *testing.T) {
+	host := bus.NewHost(NewEmulator(new(Image)))
+
+	msg := []byte{bus.AddressController, 0x42, 0x00}
+	resp := make([]byte, len(msg))
+	if n := host.Transfer(msg, resp); n != 1 {
+		t.Errorf("transferred %d bytes for a controller frame, want 1", n)
+	}
+}
@@ -0,0 +1,74 @@
+package ps1mc
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// A .mcs file holds a single save: the directory frame of its first
+// block followed by the save data.
+
+// ExportMCS writes the named save as a .mcs file.
+func (img *Image) ExportMCS(name string, w io.Writer) error {
+	s, err := img.Find(name)
+	if err != nil {
+		return err
+	}
+
+	var header [FrameSize]byte
+	writeEntry(header[:], stateFirst, s.Size, noNext, s.Name)
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	for _, b := range s.Blocks {
+		if _, err := w.Write(img.Block(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportMCS reads a .mcs file and stores the save in free blocks.
+func (img *Image) ImportMCS(r io.Reader) (Save, error) {
+	if !img.Formatted() {
+		return Save{}, ErrNotFormatted
+	}
+
+	var header [FrameSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Save{}, err
+	}
+	if header[0] != stateFirst {
+		return Save{}, ErrBadSave
+	}
+
+	size := binary.LittleEndian.Uint32(header[entrySize:])
+	n := int(size / BlockSize)
+	if size%BlockSize != 0 || n == 0 || n > SaveBlocks {
+		return Save{}, ErrBadSave
+	}
+
+	name := readName(header[:])
+	if _, err := img.Find(name); err == nil {
+		return Save{}, ErrExists
+	}
+
+	blocks, err := img.allocate(n)
+	if err != nil {
+		return Save{}, err
+	}
+
+	// Read all data before touching the card,
+	// so a short file leaves it unchanged.
+	data := make([]byte, n*BlockSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Save{}, ErrBadSave
+	}
+	for i, b := range blocks {
+		copy(img.Block(b), data[i*BlockSize:])
+	}
+	img.link(name, blocks)
+
+	return Save{Name: name, Size: size, Blocks: blocks}, nil
+}
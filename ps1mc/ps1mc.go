@@ -0,0 +1,194 @@
+// Package ps1mc reads and writes the PS1 memory card filesystem.
+//
+// A card holds 1024 frames of 128 bytes, grouped into 16 blocks of 8KiB.
+// Block 0 is the directory: a header frame, one directory frame for each
+// of the 15 save blocks, and the broken sector list. A save occupies one
+// or more blocks linked through their directory frames, and the first
+// block starts with a title frame followed by up to three icon frames.
+//
+// The package works on card images held in memory, so it runs both on
+// the board (with a card read through gpsx.MemoryCard) and on a normal
+// Go toolchain against .mcr and .mcs files.
+package ps1mc
+
+import (
+	"errors"
+	"io"
+)
+
+// Card geometry
+const (
+	FrameSize      = 128
+	FramesPerBlock = 64
+	BlockSize      = FrameSize * FramesPerBlock
+	Blocks         = 16
+	Frames         = FramesPerBlock * Blocks
+	CardSize       = BlockSize * Blocks
+
+	// SaveBlocks is the number of blocks available to saves (1-15).
+	SaveBlocks = Blocks - 1
+)
+
+// Directory block layout
+const (
+	headerFrame       = 0
+	brokenListFrame   = 16
+	brokenListEntries = 20
+	testFrame         = 63
+)
+
+// Errors
+var (
+	ErrNotFormatted = errors.New("ps1mc: card is not formatted")
+	ErrBrokenChain  = errors.New("ps1mc: broken block chain")
+	ErrNotFound     = errors.New("ps1mc: save not found")
+	ErrExists       = errors.New("ps1mc: save already exists")
+	ErrCardFull     = errors.New("ps1mc: not enough free blocks")
+	ErrBadSave      = errors.New("ps1mc: malformed save file")
+	ErrFrame        = errors.New("ps1mc: frame out of range")
+)
+
+// Device reads and writes frames of a card, such as *gpsx.MemoryCard.
+type Device interface {
+	ReadFrame(frame uint16, data []byte) error
+	WriteFrame(frame uint16, data []byte) error
+}
+
+// Image is a whole card in the raw layout of a .mcr file.
+type Image [CardSize]byte
+
+// New returns a freshly formatted card image.
+func New() *Image {
+	img := new(Image)
+	img.Format()
+	return img
+}
+
+// ReadImage reads a raw .mcr image.
+func ReadImage(r io.Reader) (*Image, error) {
+	img := new(Image)
+	if _, err := io.ReadFull(r, img[:]); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// WriteTo writes the image in the raw .mcr layout.
+func (img *Image) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(img[:])
+	return int64(n), err
+}
+
+// Load reads every frame of a card into a new image.
+func Load(dev Device) (*Image, error) {
+	img := new(Image)
+	for f := uint16(0); f < Frames; f++ {
+		if err := dev.ReadFrame(f, img.Frame(f)); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// LoadDirectory reads only what is needed to list the saves: the
+// directory block and the title frame of each save. The other frames of
+// the image are left zero, so it must not be written back to a card.
+func LoadDirectory(dev Device) (*Image, error) {
+	img := new(Image)
+	for f := uint16(0); f < FramesPerBlock; f++ {
+		if err := dev.ReadFrame(f, img.Frame(f)); err != nil {
+			return nil, err
+		}
+	}
+
+	saves, err := img.Saves()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range saves {
+		f := uint16(s.Blocks[0] * FramesPerBlock)
+		if err := dev.ReadFrame(f, img.Frame(f)); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// Store writes every frame of the image to a card.
+func (img *Image) Store(dev Device) error {
+	for f := uint16(0); f < Frames; f++ {
+		if err := dev.WriteFrame(f, img.Frame(f)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Frame returns the bytes of a frame.
+func (img *Image) Frame(frame uint16) []byte {
+	off := int(frame) * FrameSize
+	return img[off : off+FrameSize]
+}
+
+// ReadFrame copies a frame into data. It makes an Image a Device.
+func (img *Image) ReadFrame(frame uint16, data []byte) error {
+	if frame >= Frames {
+		return ErrFrame
+	}
+	copy(data, img.Frame(frame))
+	return nil
+}
+
+// WriteFrame copies data into a frame. It makes an Image a Device.
+func (img *Image) WriteFrame(frame uint16, data []byte) error {
+	if frame >= Frames {
+		return ErrFrame
+	}
+	copy(img.Frame(frame), data)
+	return nil
+}
+
+// Block returns the bytes of a block.
+func (img *Image) Block(block int) []byte {
+	off := block * BlockSize
+	return img[off : off+BlockSize]
+}
+
+// Formatted returns true if the image carries the card header.
+func (img *Image) Formatted() bool {
+	h := img.Frame(headerFrame)
+	return h[0] == 'M' && h[1] == 'C' && checksum(h) == h[FrameSize-1]
+}
+
+// Format erases the directory, leaving every save block free.
+// The save data itself is left untouched.
+func (img *Image) Format() {
+	h := img.Frame(headerFrame)
+	clear(h)
+	h[0] = 'M'
+	h[1] = 'C'
+	h[FrameSize-1] = checksum(h)
+
+	for b := 1; b <= SaveBlocks; b++ {
+		writeEntry(img.Frame(uint16(b)), stateFree, 0, noNext, "")
+	}
+
+	for i := 0; i < brokenListEntries; i++ {
+		f := img.Frame(uint16(brokenListFrame + i))
+		clear(f)
+		f[0], f[1], f[2], f[3] = 0xFF, 0xFF, 0xFF, 0xFF
+		f[8], f[9] = 0xFF, 0xFF
+		f[FrameSize-1] = checksum(f)
+	}
+
+	copy(img.Frame(testFrame), h)
+}
+
+// checksum returns the XOR of all bytes of a frame but the last.
+func checksum(frame []byte) byte {
+	var sum byte
+	for _, b := range frame[:FrameSize-1] {
+		sum ^= b
+	}
+	return sum
+}
@@ -0,0 +1,196 @@
+package ps1mc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// mcs builds a .mcs file for a save of n blocks filled from seed.
+func mcs(name string, n int, seed byte) []byte {
+	buf := make([]byte, FrameSize+n*BlockSize)
+	writeEntry(buf, stateFirst, uint32(n*BlockSize), noNext, name)
+	for i := FrameSize; i < len(buf); i++ {
+		buf[i] = seed + byte(i/FrameSize)
+	}
+	return buf
+}
+
+func TestFormat(t *testing.T) {
+	img := new(Image)
+	if img.Formatted() {
+		t.Fatal("blank image reads as formatted")
+	}
+	if _, err := img.Saves(); err != ErrNotFormatted {
+		t.Errorf("Saves on a blank image: %v, want %v", err, ErrNotFormatted)
+	}
+
+	img = New()
+	if !img.Formatted() {
+		t.Fatal("New image not formatted")
+	}
+	if err := img.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	if n := img.FreeBlocks(); n != SaveBlocks {
+		t.Errorf("FreeBlocks %d, want %d", n, SaveBlocks)
+	}
+	if saves, err := img.Saves(); err != nil || len(saves) != 0 {
+		t.Errorf("Saves %v, %v, want none", saves, err)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	img := New()
+	a := mcs("BASCUS-94163FF7", 1, 0x10)
+	b := mcs("BESLES-01234GAME", 3, 0x40)
+
+	for _, file := range [][]byte{a, b} {
+		if _, err := img.ImportMCS(bytes.NewReader(file)); err != nil {
+			t.Fatalf("ImportMCS: %v", err)
+		}
+	}
+	if err := img.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	if n := img.FreeBlocks(); n != SaveBlocks-4 {
+		t.Errorf("FreeBlocks %d, want %d", n, SaveBlocks-4)
+	}
+
+	saves, err := img.Saves()
+	if err != nil || len(saves) != 2 {
+		t.Fatalf("Saves %v, %v, want two", saves, err)
+	}
+	if s := saves[1]; s.Name != "BESLES-01234GAME" || s.Size != 3*BlockSize || len(s.Blocks) != 3 || s.Blocks[0] != 2 {
+		t.Errorf("second save %+v", s)
+	}
+
+	for _, file := range [][]byte{a, b} {
+		var out bytes.Buffer
+		if err := img.ExportMCS(readName(file), &out); err != nil {
+			t.Fatalf("ExportMCS: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), file) {
+			t.Errorf("%s: export differs from the imported file", readName(file))
+		}
+	}
+
+	if _, err := img.ImportMCS(bytes.NewReader(a)); err != ErrExists {
+		t.Errorf("second import: %v, want %v", err, ErrExists)
+	}
+	if _, err := img.Find("BASLUS-00000"); err != ErrNotFound {
+		t.Errorf("Find missing save: %v, want %v", err, ErrNotFound)
+	}
+
+	if err := img.Delete("BASCUS-94163FF7"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n := img.FreeBlocks(); n != SaveBlocks-3 {
+		t.Errorf("FreeBlocks %d after delete, want %d", n, SaveBlocks-3)
+	}
+}
+
+func TestImportCardFull(t *testing.T) {
+	img := New()
+	if _, err := img.ImportMCS(bytes.NewReader(mcs("BIG", 14, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := img.ImportMCS(bytes.NewReader(mcs("TWO", 2, 0))); err != ErrCardFull {
+		t.Errorf("import into one free block: %v, want %v", err, ErrCardFull)
+	}
+}
+
+func TestImportTruncated(t *testing.T) {
+	img := New()
+
+	// A deleted save in the free blocks must survive a failed import
+	deleted := mcs("OLD", 2, 0x70)
+	if _, err := img.ImportMCS(bytes.NewReader(deleted)); err != nil {
+		t.Fatal(err)
+	}
+	img.Delete("OLD")
+	before := *img
+
+	file := mcs("BASCUS-94163FF7", 2, 0x10)
+	for _, n := range []int{FrameSize + BlockSize, len(file) - 1} {
+		if _, err := img.ImportMCS(bytes.NewReader(file[:n])); err != ErrBadSave {
+			t.Errorf("%d bytes: %v, want %v", n, err, ErrBadSave)
+		}
+		if *img != before {
+			t.Errorf("%d bytes: card changed by a failed import", n)
+		}
+	}
+
+	bad := mcs("ODD", 1, 0)
+	binary.LittleEndian.PutUint32(bad[entrySize:], BlockSize+1)
+	if _, err := img.ImportMCS(bytes.NewReader(bad)); err != ErrBadSave {
+		t.Errorf("odd size: %v, want %v", err, ErrBadSave)
+	}
+}
+
+func TestBrokenChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(img *Image)
+	}{
+		{"next out of range", func(img *Image) {
+			e := img.Frame(1)
+			binary.LittleEndian.PutUint16(e[entryNext:], 20)
+			e[FrameSize-1] = checksum(e)
+		}},
+		{"next to a free block", func(img *Image) {
+			e := img.Frame(1)
+			binary.LittleEndian.PutUint16(e[entryNext:], 9)
+			e[FrameSize-1] = checksum(e)
+		}},
+		{"loop", func(img *Image) {
+			// Middle block points back to itself
+			e := img.Frame(2)
+			binary.LittleEndian.PutUint16(e[entryNext:], 1)
+			e[FrameSize-1] = checksum(e)
+		}},
+		{"orphan middle block", func(img *Image) {
+			writeEntry(img.Frame(1), stateFree, 0, noNext, "")
+		}},
+		{"bad directory checksum", func(img *Image) {
+			img.Frame(3)[FrameSize-1] ^= 0xFF
+		}},
+	}
+	for _, tt := range tests {
+		img := New()
+		if _, err := img.ImportMCS(bytes.NewReader(mcs("SAVE", 3, 0))); err != nil {
+			t.Fatal(err)
+		}
+		tt.corrupt(img)
+		if err := img.Check(); err != ErrBrokenChain {
+			t.Errorf("%s: Check %v, want %v", tt.name, err, ErrBrokenChain)
+		}
+	}
+}
+
+func TestIcon(t *testing.T) {
+	img := New()
+	file := mcs("SAVE", 1, 0)
+	data := file[FrameSize:]
+	data[titleIconFlag] = 0x12 // Two frames
+	for i := range data[FrameSize : 3*FrameSize] {
+		data[FrameSize+i] = 0x21
+	}
+	s, err := img.ImportMCS(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := img.IconFrames(s); n != 2 {
+		t.Fatalf("IconFrames %d, want 2", n)
+	}
+	if icon := img.Icon(s, 1); icon[0] != 1 || icon[1] != 2 {
+		t.Errorf("icon frame 1 starts %d %d, want 1 2", icon[0], icon[1])
+	}
+	var blank [IconSize * IconSize]uint8
+	for _, f := range []int{-1, 2, 62, 1000} {
+		if img.Icon(s, f) != blank {
+			t.Errorf("frame %d: not blank", f)
+		}
+	}
+}
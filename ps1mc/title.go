@@ -0,0 +1,96 @@
+package ps1mc
+
+import "encoding/binary"
+
+// Title frame layout (first frame of a save's first block)
+const (
+	titleIconFlag = 0x02
+	titleText     = 0x04
+	titleTextLen  = 64
+	titlePalette  = 0x60
+
+	// Icon size in pixels
+	IconSize = 16
+)
+
+// Title returns the Shift-JIS title of a save, trimmed at the first zero.
+func (img *Image) Title(s Save) []byte {
+	t := img.Block(s.Blocks[0])[titleText : titleText+titleTextLen]
+	for i, c := range t {
+		if c == 0 {
+			return t[:i]
+		}
+	}
+	return t
+}
+
+// TitleASCII returns the title with full-width Shift-JIS letters, digits
+// and spaces converted to ASCII. Other characters are replaced by '?'.
+func (img *Image) TitleASCII(s Save) string {
+	t := img.Title(s)
+	out := make([]byte, 0, len(t)/2)
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if c < 0x80 {
+			out = append(out, c)
+			continue
+		}
+		if i+1 >= len(t) {
+			break
+		}
+		code := uint16(c)<<8 | uint16(t[i+1])
+		i++
+		switch {
+		case code == 0x8140:
+			out = append(out, ' ')
+		case code >= 0x824F && code <= 0x8258:
+			out = append(out, byte('0'+code-0x824F))
+		case code >= 0x8260 && code <= 0x8279:
+			out = append(out, byte('A'+code-0x8260))
+		case code >= 0x8281 && code <= 0x829A:
+			out = append(out, byte('a'+code-0x8281))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return string(out)
+}
+
+// IconFrames returns the number of icon animation frames (1-3).
+func (img *Image) IconFrames(s Save) int {
+	n := int(img.Block(s.Blocks[0])[titleIconFlag] & 0x0F)
+	if n < 1 || n > 3 {
+		return 1
+	}
+	return n
+}
+
+// IconPalette returns the 16-color icon palette in 15-bit BGR format.
+func (img *Image) IconPalette(s Save) [16]uint16 {
+	var pal [16]uint16
+	p := img.Block(s.Blocks[0])[titlePalette:]
+	for i := range pal {
+		pal[i] = binary.LittleEndian.Uint16(p[i*2:])
+	}
+	return pal
+}
+
+// Icon returns the palette indices of an icon frame, row by row. A frame
+// outside [0, IconFrames(s)) gives a blank icon.
+func (img *Image) Icon(s Save, frame int) [IconSize * IconSize]uint8 {
+	var icon [IconSize * IconSize]uint8
+	if frame < 0 || frame >= img.IconFrames(s) {
+		return icon
+	}
+	data := img.Block(s.Blocks[0])[(frame+1)*FrameSize:]
+	for i := range icon {
+		// Two pixels per byte, left pixel in the low nibble
+		b := data[i/2]
+		if i%2 == 0 {
+			icon[i] = b & 0x0F
+		} else {
+			icon[i] = b >> 4
+		}
+	}
+	return icon
+}
@@ -0,0 +1,63 @@
+package ps2mc
+
+// Check walks every directory from the root and validates the cluster
+// chain of each file and directory: chains must end within the card,
+// cover the entry's size, and no cluster may belong to two chains.
+func (fs *FS) Check() error {
+	root, err := fs.root()
+	if err != nil {
+		return err
+	}
+
+	used := make([]bool, fs.sb.AllocEnd)
+	return fs.checkDir(root, used)
+}
+
+// checkDir validates a directory and, recursively, its entries.
+func (fs *FS) checkDir(dir DirEntry, used []bool) error {
+	size, err := fs.dirSize(dir)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkChain(dir.Cluster, size, used); err != nil {
+		return err
+	}
+
+	entries, err := fs.entries(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Exists() || e.Name == "." || e.Name == ".." {
+			continue
+		}
+		if e.IsDir() {
+			err = fs.checkDir(e, used)
+		} else if e.Size > 0 {
+			err = fs.checkChain(e.Cluster, e.Size, used)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChain validates one chain holding size bytes and marks its clusters.
+func (fs *FS) checkChain(cluster uint32, size uint32, used []bool) error {
+	clusters, err := fs.chain(cluster)
+	if err != nil {
+		return err
+	}
+	if uint32(len(clusters))*fs.clusterSize < size {
+		return ErrBadChain
+	}
+
+	for _, c := range clusters {
+		if used[c] {
+			return ErrCrossLinked
+		}
+		used[c] = true
+	}
+	return nil
+}
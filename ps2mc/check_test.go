@@ -0,0 +1,76 @@
+package ps2mc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testCard builds a bare dump with 1 KiB clusters: superblock, indirect
+// FAT, FAT, then five allocatable clusters holding an empty root
+// directory whose "." entry claims size entries.
+func testCard(t *testing.T, size uint32) *FS {
+	const cluster = 2 * PageSize
+	img := make([]byte, 8*cluster)
+
+	sb := img[:cluster]
+	copy(sb, superblockMagic)
+	binary.LittleEndian.PutUint16(sb[sbPageLen:], PageSize)
+	binary.LittleEndian.PutUint16(sb[sbPagesPerCluster:], 2)
+	binary.LittleEndian.PutUint16(sb[sbPagesPerBlock:], PagesPerBlock)
+	binary.LittleEndian.PutUint32(sb[sbClustersPerCard:], 8)
+	binary.LittleEndian.PutUint32(sb[sbAllocOffset:], 3)
+	binary.LittleEndian.PutUint32(sb[sbAllocEnd:], 5)
+	binary.LittleEndian.PutUint32(sb[sbIFCList:], 1)
+
+	binary.LittleEndian.PutUint32(img[1*cluster:], 2)
+	binary.LittleEndian.PutUint32(img[2*cluster:], fatChainEnd)
+
+	root := img[3*cluster:]
+	for i, name := range []string{".", ".."} {
+		e := root[i*DirEntrySize:]
+		binary.LittleEndian.PutUint16(e[deMode:], ModeDir|ModeExists)
+		binary.LittleEndian.PutUint32(e[deLength:], size)
+		copy(e[deName:], name)
+	}
+
+	dev, err := NewImageFile(bytes.NewReader(img), int64(len(img)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := Open(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestCheckEmptyRoot(t *testing.T) {
+	fs := testCard(t, 2)
+	if err := fs.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	entries, err := fs.ReadDir("/")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir: %v, %v", entries, err)
+	}
+}
+
+func TestCheckDirSizeOverflow(t *testing.T) {
+	// 0x800001 entries of 512 bytes wrap around to a single entry
+	fs := testCard(t, 0x800001)
+	if err := fs.Check(); err != ErrBadChain {
+		t.Errorf("Check: got %v, want %v", err, ErrBadChain)
+	}
+	if _, err := fs.ReadDir("/"); err != ErrBadChain {
+		t.Errorf("ReadDir: got %v, want %v", err, ErrBadChain)
+	}
+}
+
+func TestCheckDirSizeTooLarge(t *testing.T) {
+	// More entries than the single cluster of the chain holds
+	fs := testCard(t, 3)
+	if err := fs.Check(); err != ErrBadChain {
+		t.Errorf("Check: got %v, want %v", err, ErrBadChain)
+	}
+}
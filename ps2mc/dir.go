@@ -0,0 +1,228 @@
+package ps2mc
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"time"
+)
+
+// Directory entry size
+const DirEntrySize = 512
+
+// Directory entry layout
+const (
+	deMode     = 0x00
+	deLength   = 0x04
+	deCreated  = 0x08
+	deCluster  = 0x10
+	deParent   = 0x14
+	deModified = 0x18
+	deAttr     = 0x20
+	deName     = 0x40
+	deNameLen  = 32
+)
+
+// Mode flags of a directory entry
+const (
+	ModeRead      uint16 = 0x0001
+	ModeWrite     uint16 = 0x0002
+	ModeExecute   uint16 = 0x0004
+	ModeProtected uint16 = 0x0008
+	ModeFile      uint16 = 0x0010
+	ModeDir       uint16 = 0x0020
+	ModeHidden    uint16 = 0x2000
+	ModeExists    uint16 = 0x8000
+)
+
+// Timestamps on the card are in Japan Standard Time
+var cardZone = time.FixedZone("JST", 9*60*60)
+
+// DirEntry describes a file or directory.
+type DirEntry struct {
+	Name     string
+	Mode     uint16
+	Size     uint32 // Bytes for a file, entries for a directory
+	Cluster  uint32 // First cluster, relative to AllocOffset
+	Created  time.Time
+	Modified time.Time
+	Attr     uint32
+}
+
+// IsDir returns true if the entry is a directory.
+func (e DirEntry) IsDir() bool {
+	return e.Mode&ModeDir != 0
+}
+
+// Exists returns true if the entry is in use.
+func (e DirEntry) Exists() bool {
+	return e.Mode&ModeExists != 0
+}
+
+// root returns the entry of the root directory, taken from its "." entry.
+func (fs *FS) root() (DirEntry, error) {
+	data, err := fs.readChain(fs.sb.RootDirCluster, DirEntrySize)
+	if err != nil {
+		return DirEntry{}, err
+	}
+	e := parseDirEntry(data)
+	e.Cluster = fs.sb.RootDirCluster
+	return e, nil
+}
+
+// entries returns all entries of a directory, including "." and "..".
+func (fs *FS) entries(dir DirEntry) ([]DirEntry, error) {
+	if !dir.IsDir() {
+		return nil, ErrNotDir
+	}
+	size, err := fs.dirSize(dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.readChain(dir.Cluster, size)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]DirEntry, len(data)/DirEntrySize)
+	for i := range entries {
+		entries[i] = parseDirEntry(data[i*DirEntrySize:])
+	}
+	return entries, nil
+}
+
+// dirSize returns the size in bytes of the entries of a directory. A
+// directory can't hold more entries than fit in the whole card.
+func (fs *FS) dirSize(dir DirEntry) (uint32, error) {
+	max := uint64(fs.sb.AllocEnd) * uint64(fs.clusterSize) / DirEntrySize
+	if uint64(dir.Size) > max || dir.Size > math.MaxUint32/DirEntrySize {
+		return 0, ErrBadChain
+	}
+	return dir.Size * DirEntrySize, nil
+}
+
+// ReadDir returns the entries of a directory that are in use, without
+// "." and "..". The path is slash separated, "/" is the root directory.
+func (fs *FS) ReadDir(path string) ([]DirEntry, error) {
+	dir, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	all, err := fs.entries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DirEntry
+	for _, e := range all {
+		if e.Exists() && e.Name != "." && e.Name != ".." {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Stat returns the entry for a path.
+func (fs *FS) Stat(path string) (DirEntry, error) {
+	e, err := fs.root()
+	if err != nil {
+		return DirEntry{}, err
+	}
+
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+		entries, err := fs.entries(e)
+		if err != nil {
+			return DirEntry{}, err
+		}
+
+		found := false
+		for _, c := range entries {
+			if c.Exists() && c.Name == name {
+				e = c
+				found = true
+				break
+			}
+		}
+		if !found {
+			return DirEntry{}, ErrNotFound
+		}
+	}
+	return e, nil
+}
+
+// ReadFile returns the contents of a file.
+func (fs *FS) ReadFile(path string) ([]byte, error) {
+	e, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if e.IsDir() {
+		return nil, ErrIsDir
+	}
+	if e.Size == 0 {
+		return nil, nil
+	}
+	return fs.readChain(e.Cluster, e.Size)
+}
+
+// Saves returns the save directories in the root directory.
+func (fs *FS) Saves() ([]DirEntry, error) {
+	entries, err := fs.ReadDir("/")
+	if err != nil {
+		return nil, err
+	}
+
+	var saves []DirEntry
+	for _, e := range entries {
+		if e.IsDir() {
+			saves = append(saves, e)
+		}
+	}
+	return saves, nil
+}
+
+// Extract calls fn with every file of a directory and its contents,
+// such as the icon.sys, icons and data files of a save.
+func (fs *FS) Extract(path string, fn func(e DirEntry, data []byte) error) error {
+	entries, err := fs.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(path + "/" + e.Name)
+		if err != nil {
+			return err
+		}
+		if err := fn(e, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDirEntry decodes a 512-byte directory entry.
+func parseDirEntry(b []byte) DirEntry {
+	return DirEntry{
+		Name:     cString(b[deName : deName+deNameLen]),
+		Mode:     binary.LittleEndian.Uint16(b[deMode:]),
+		Size:     binary.LittleEndian.Uint32(b[deLength:]),
+		Cluster:  binary.LittleEndian.Uint32(b[deCluster:]),
+		Created:  parseTime(b[deCreated:]),
+		Modified: parseTime(b[deModified:]),
+		Attr:     binary.LittleEndian.Uint32(b[deAttr:]),
+	}
+}
+
+// parseTime decodes an 8-byte timestamp:
+// unused, second, minute, hour, day, month, year (16 bits).
+func parseTime(b []byte) time.Time {
+	year := int(binary.LittleEndian.Uint16(b[6:]))
+	return time.Date(year, time.Month(b[5]), int(b[4]), int(b[3]), int(b[2]), int(b[1]), 0, cardZone)
+}
@@ -0,0 +1,136 @@
+package ps2mc
+
+// ECCResult is the outcome of an ECC check.
+type ECCResult uint8
+
+// ECC check results
+const (
+	ECCOk        ECCResult = iota // Data and code agree
+	ECCCorrected                  // A single bit error was corrected
+	ECCFailed                     // Uncorrectable error
+)
+
+// ECCSize is the size of the Hamming code of one chunk.
+const ECCSize = 3
+
+// Masks selecting the bits that feed each column parity bit
+var columnParityMasks = [7]uint8{0x55, 0x33, 0x0F, 0x00, 0xAA, 0xCC, 0xF0}
+
+// parityTable and columnParityTable hold the byte parity and column parity contribution of every byte value.
+var parityTable, columnParityTable = makeECCTables()
+
+// makeECCTables builds the parity lookup tables.
+func makeECCTables() (parity [256]uint8, column [256]uint8) {
+	for b := 0; b < 256; b++ {
+		parity[b] = parityOf(uint8(b))
+	}
+	for b := 0; b < 256; b++ {
+		var mask uint8
+		for i, m := range columnParityMasks {
+			mask |= parity[uint8(b)&m] << i
+		}
+		column[b] = mask
+	}
+	return
+}
+
+// parityOf returns 1 if b has an odd number of bits set.
+func parityOf(b uint8) uint8 {
+	b ^= b >> 4
+	b ^= b >> 2
+	b ^= b >> 1
+	return b & 1
+}
+
+// popcount returns the number of bits set in b.
+func popcount(b uint8) int {
+	n := 0
+	for ; b != 0; b &= b - 1 {
+		n++
+	}
+	return n
+}
+
+// erased reports whether every byte of b is 0xFF.
+func erased(b []byte) bool {
+	for _, v := range b {
+		if v != 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+// ChunkECC returns the Hamming code of a 128-byte chunk.
+func ChunkECC(chunk []byte) [ECCSize]byte {
+	var columnParity uint8 = 0x77
+	var lineParity0 uint8 = 0x7F
+	var lineParity1 uint8 = 0x7F
+	for i, b := range chunk[:ChunkSize] {
+		columnParity ^= columnParityTable[b]
+		if parityTable[b] != 0 {
+			lineParity0 ^= ^uint8(i)
+			lineParity1 ^= uint8(i)
+		}
+	}
+	return [ECCSize]byte{columnParity, lineParity0 & 0x7F, lineParity1}
+}
+
+// CheckChunk verifies a chunk against its code and corrects a single bit
+// error in either of them in place.
+func CheckChunk(chunk []byte, ecc []byte) ECCResult {
+	computed := ChunkECC(chunk)
+	if computed[0] == ecc[0] && computed[1] == ecc[1] && computed[2] == ecc[2] {
+		return ECCOk
+	}
+
+	// An erased page has no code written yet
+	if erased(chunk) && erased(ecc) {
+		return ECCOk
+	}
+
+	cpDiff := (computed[0] ^ ecc[0]) & 0x77
+	lp0Diff := (computed[1] ^ ecc[1]) & 0x7F
+	lp1Diff := (computed[2] ^ ecc[2]) & 0x7F
+	lpComp := lp0Diff ^ lp1Diff
+	cpComp := (cpDiff >> 4) ^ (cpDiff & 0x07)
+
+	// Single bit error in the data: the line parity gives the byte,
+	// the column parity gives the bit
+	if lpComp == 0x7F && cpComp == 0x07 {
+		chunk[lp1Diff] ^= 1 << (cpDiff >> 4)
+		return ECCCorrected
+	}
+
+	// Single bit error in the code itself (or an unused bit set)
+	if (cpDiff == 0 && lp0Diff == 0 && lp1Diff == 0) || popcount(lpComp)+popcount(cpComp) == 1 {
+		copy(ecc, computed[:])
+		return ECCCorrected
+	}
+
+	return ECCFailed
+}
+
+// PageECC fills the spare area with the codes of a 512-byte page.
+func PageECC(page []byte, spare []byte) {
+	for i := range spare[:SpareSize] {
+		spare[i] = 0
+	}
+	for c := 0; c < ChunksPerPage; c++ {
+		ecc := ChunkECC(page[c*ChunkSize:])
+		copy(spare[c*ECCSize:], ecc[:])
+	}
+}
+
+// CheckPage verifies a 512-byte page against its spare area, correcting
+// single bit errors in place. It returns the worst result of all chunks.
+func CheckPage(page []byte, spare []byte) ECCResult {
+	result := ECCOk
+	for c := 0; c < ChunksPerPage; c++ {
+		r := CheckChunk(page[c*ChunkSize:(c+1)*ChunkSize], spare[c*ECCSize:(c+1)*ECCSize])
+		if r > result {
+			result = r
+		}
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package ps2mc
+
+import "testing"
+
+func TestCheckPageErased(t *testing.T) {
+	var page [PageSize]byte
+	var spare [SpareSize]byte
+	for i := range page {
+		page[i] = 0xFF
+	}
+	for i := range spare {
+		spare[i] = 0xFF
+	}
+	if r := CheckPage(page[:], spare[:]); r != ECCOk {
+		t.Errorf("erased page: got %d, want ECCOk", r)
+	}
+}
+
+func TestCheckPageCorrects(t *testing.T) {
+	var page [PageSize]byte
+	var spare [SpareSize]byte
+	for i := range page {
+		page[i] = byte(i * 7)
+	}
+	PageECC(page[:], spare[:])
+	if r := CheckPage(page[:], spare[:]); r != ECCOk {
+		t.Fatalf("clean page: got %d, want ECCOk", r)
+	}
+
+	want := page
+	page[300] ^= 0x10
+	if r := CheckPage(page[:], spare[:]); r != ECCCorrected {
+		t.Fatalf("flipped bit: got %d, want ECCCorrected", r)
+	}
+	if page != want {
+		t.Errorf("page not restored")
+	}
+
+	page[5] ^= 0x01
+	page[6] ^= 0x01
+	if r := CheckPage(page[:], spare[:]); r != ECCFailed {
+		t.Errorf("two flipped bits: got %d, want ECCFailed", r)
+	}
+}
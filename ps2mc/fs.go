@@ -0,0 +1,232 @@
+package ps2mc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Superblock magic
+const superblockMagic = "Sony PS2 Memory Card Format "
+
+// FAT entry values
+const (
+	fatAllocated   uint32 = 0x80000000
+	fatClusterMask uint32 = 0x7FFFFFFF
+	fatChainEnd    uint32 = 0xFFFFFFFF
+)
+
+// Superblock layout
+const (
+	sbVersion         = 0x1C
+	sbPageLen         = 0x28
+	sbPagesPerCluster = 0x2A
+	sbPagesPerBlock   = 0x2C
+	sbClustersPerCard = 0x30
+	sbAllocOffset     = 0x34
+	sbAllocEnd        = 0x38
+	sbRootDirCluster  = 0x3C
+	sbBackupBlock1    = 0x40
+	sbBackupBlock2    = 0x44
+	sbIFCList         = 0x50
+	sbBadBlockList    = 0xD0
+	sbCardType        = 0x150
+	sbCardFlags       = 0x151
+
+	ifcListLen = 32
+)
+
+// Errors
+var (
+	ErrNotFormatted = errors.New("ps2mc: no superblock")
+	ErrBadChain     = errors.New("ps2mc: broken cluster chain")
+	ErrCrossLinked  = errors.New("ps2mc: cluster used twice")
+	ErrNotFound     = errors.New("ps2mc: no such file or directory")
+	ErrNotDir       = errors.New("ps2mc: not a directory")
+	ErrIsDir        = errors.New("ps2mc: is a directory")
+)
+
+// Superblock is the card header stored in page 0.
+type Superblock struct {
+	Version          string
+	PageSize         uint16
+	PagesPerCluster  uint16
+	PagesPerBlock    uint16
+	ClustersPerCard  uint32
+	AllocOffset      uint32 // First allocatable cluster
+	AllocEnd         uint32 // Number of allocatable clusters
+	RootDirCluster   uint32 // First cluster of the root directory, relative to AllocOffset
+	BackupBlock1     uint32
+	BackupBlock2     uint32
+	IndirectClusters [ifcListLen]uint32 // Indirect FAT clusters
+	BadBlocks        [ifcListLen]uint32
+	CardType         uint8
+	CardFlags        uint8
+}
+
+// FS reads the filesystem of a PS2 memory card.
+type FS struct {
+	dev PageReader
+	sb  Superblock
+
+	clusterSize uint32
+
+	// Last indirect and FAT clusters read, cached for FAT lookups
+	indirect      []byte
+	indirectIndex uint32
+	fat           []byte
+	fatIndex      uint32
+
+	page  [PageSize]byte
+	spare [SpareSize]byte
+}
+
+// Open reads the superblock and returns the filesystem of a card.
+func Open(dev PageReader) (*FS, error) {
+	fs := &FS{dev: dev}
+	if err := dev.ReadPage(0, fs.page[:], fs.spare[:]); err != nil {
+		return nil, err
+	}
+
+	p := fs.page[:]
+	if !bytes.HasPrefix(p, []byte(superblockMagic)) {
+		return nil, ErrNotFormatted
+	}
+
+	sb := &fs.sb
+	sb.Version = cString(p[sbVersion:sbPageLen])
+	sb.PageSize = binary.LittleEndian.Uint16(p[sbPageLen:])
+	sb.PagesPerCluster = binary.LittleEndian.Uint16(p[sbPagesPerCluster:])
+	sb.PagesPerBlock = binary.LittleEndian.Uint16(p[sbPagesPerBlock:])
+	sb.ClustersPerCard = binary.LittleEndian.Uint32(p[sbClustersPerCard:])
+	sb.AllocOffset = binary.LittleEndian.Uint32(p[sbAllocOffset:])
+	sb.AllocEnd = binary.LittleEndian.Uint32(p[sbAllocEnd:])
+	sb.RootDirCluster = binary.LittleEndian.Uint32(p[sbRootDirCluster:])
+	sb.BackupBlock1 = binary.LittleEndian.Uint32(p[sbBackupBlock1:])
+	sb.BackupBlock2 = binary.LittleEndian.Uint32(p[sbBackupBlock2:])
+	for i := range sb.IndirectClusters {
+		sb.IndirectClusters[i] = binary.LittleEndian.Uint32(p[sbIFCList+i*4:])
+		sb.BadBlocks[i] = binary.LittleEndian.Uint32(p[sbBadBlockList+i*4:])
+	}
+	sb.CardType = p[sbCardType]
+	sb.CardFlags = p[sbCardFlags]
+
+	if sb.PageSize != PageSize || sb.PagesPerCluster == 0 || sb.AllocEnd == 0 {
+		return nil, ErrNotFormatted
+	}
+
+	fs.clusterSize = uint32(sb.PageSize) * uint32(sb.PagesPerCluster)
+	fs.indirect = make([]byte, fs.clusterSize)
+	fs.fat = make([]byte, fs.clusterSize)
+	fs.indirectIndex = fatChainEnd
+	fs.fatIndex = fatChainEnd
+	return fs, nil
+}
+
+// Superblock returns the card header.
+func (fs *FS) Superblock() Superblock {
+	return fs.sb
+}
+
+// ClusterSize returns the size of a cluster in bytes.
+func (fs *FS) ClusterSize() uint32 {
+	return fs.clusterSize
+}
+
+// readCluster reads an absolute cluster into buf.
+func (fs *FS) readCluster(cluster uint32, buf []byte) error {
+	ppc := uint32(fs.sb.PagesPerCluster)
+	for i := uint32(0); i < ppc; i++ {
+		if err := fs.dev.ReadPage(cluster*ppc+i, buf[i*PageSize:(i+1)*PageSize], fs.spare[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fatEntry returns the FAT entry of an allocatable cluster. The FAT is
+// reached through two levels of indirection: the superblock lists the
+// indirect FAT clusters, which list the FAT clusters.
+func (fs *FS) fatEntry(cluster uint32) (uint32, error) {
+	perCluster := fs.clusterSize / 4
+
+	fatOffset := cluster % perCluster
+	indirectOffset := (cluster / perCluster) % perCluster
+	listOffset := cluster / perCluster / perCluster
+	if listOffset >= ifcListLen {
+		return 0, ErrBadChain
+	}
+
+	indirect := fs.sb.IndirectClusters[listOffset]
+	if indirect != fs.indirectIndex {
+		if err := fs.readCluster(indirect, fs.indirect); err != nil {
+			return 0, err
+		}
+		fs.indirectIndex = indirect
+	}
+
+	fat := binary.LittleEndian.Uint32(fs.indirect[indirectOffset*4:])
+	if fat != fs.fatIndex {
+		if err := fs.readCluster(fat, fs.fat); err != nil {
+			return 0, err
+		}
+		fs.fatIndex = fat
+	}
+
+	return binary.LittleEndian.Uint32(fs.fat[fatOffset*4:]), nil
+}
+
+// chain returns the clusters of the chain starting at cluster, relative
+// to AllocOffset.
+func (fs *FS) chain(cluster uint32) ([]uint32, error) {
+	var clusters []uint32
+	for {
+		if cluster >= fs.sb.AllocEnd || uint32(len(clusters)) >= fs.sb.AllocEnd {
+			return nil, ErrBadChain
+		}
+		clusters = append(clusters, cluster)
+
+		entry, err := fs.fatEntry(cluster)
+		if err != nil {
+			return nil, err
+		}
+		if entry == fatChainEnd {
+			return clusters, nil
+		}
+		if entry&fatAllocated == 0 {
+			return nil, ErrBadChain
+		}
+		cluster = entry & fatClusterMask
+	}
+}
+
+// readChain reads size bytes from the chain starting at cluster.
+func (fs *FS) readChain(cluster uint32, size uint32) ([]byte, error) {
+	clusters, err := fs.chain(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(clusters))*fs.clusterSize < size {
+		return nil, ErrBadChain
+	}
+
+	data := make([]byte, uint32(len(clusters))*fs.clusterSize)
+	for i, c := range clusters {
+		off := uint32(i) * fs.clusterSize
+		if off >= size {
+			break
+		}
+		if err := fs.readCluster(fs.sb.AllocOffset+c, data[off:off+fs.clusterSize]); err != nil {
+			return nil, err
+		}
+	}
+	return data[:size], nil
+}
+
+// cString returns b up to its first zero byte.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,212 @@
+package ps2mc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// saveCard builds a bare dump with 1 KiB clusters and one save, with
+// every chain split across clusters out of order:
+//
+//	cluster 0    superblock
+//	cluster 1    FAT
+//	cluster 2    indirect FAT, listing cluster 1
+//	alloc 0, 5   root directory: ".", "..", "BESLES-12345"
+//	alloc 1, 2   save directory: ".", "..", "icon.sys", "DATA"
+//	alloc 3      icon.sys, 964 bytes
+//	alloc 6,4,7  DATA, 2500 bytes
+//	alloc 8      free
+func saveCard(t *testing.T) (*FS, map[string][]byte) {
+	const (
+		cluster = 2 * PageSize
+		offset  = 3
+	)
+	img := make([]byte, (offset+9)*cluster)
+
+	sb := img[:cluster]
+	copy(sb, superblockMagic)
+	copy(sb[sbVersion:], "1.2.0.0")
+	binary.LittleEndian.PutUint16(sb[sbPageLen:], PageSize)
+	binary.LittleEndian.PutUint16(sb[sbPagesPerCluster:], 2)
+	binary.LittleEndian.PutUint16(sb[sbPagesPerBlock:], PagesPerBlock)
+	binary.LittleEndian.PutUint32(sb[sbClustersPerCard:], offset+9)
+	binary.LittleEndian.PutUint32(sb[sbAllocOffset:], offset)
+	binary.LittleEndian.PutUint32(sb[sbAllocEnd:], 9)
+	binary.LittleEndian.PutUint32(sb[sbIFCList:], 2)
+	binary.LittleEndian.PutUint32(img[2*cluster:], 1)
+
+	fat := img[1*cluster:]
+	link := func(chain ...uint32) {
+		for i, c := range chain {
+			next := fatChainEnd
+			if i+1 < len(chain) {
+				next = fatAllocated | chain[i+1]
+			}
+			binary.LittleEndian.PutUint32(fat[c*4:], next)
+		}
+	}
+	link(0, 5)
+	link(1, 2)
+	link(3)
+	link(6, 4, 7)
+	binary.LittleEndian.PutUint32(fat[8*4:], fatClusterMask)
+
+	// write stores data across the clusters of a chain
+	write := func(data []byte, chain ...uint32) {
+		for i, c := range chain {
+			off := i * cluster
+			if off >= len(data) {
+				break
+			}
+			copy(img[(offset+c)*cluster:], data[off:min(len(data), off+cluster)])
+		}
+	}
+	// entry encodes a directory entry
+	entry := func(name string, mode uint16, size, first uint32) []byte {
+		e := make([]byte, DirEntrySize)
+		binary.LittleEndian.PutUint16(e[deMode:], mode|ModeExists)
+		binary.LittleEndian.PutUint32(e[deLength:], size)
+		binary.LittleEndian.PutUint32(e[deCluster:], first)
+		// 2024-03-15 12:34:56 JST
+		binary.LittleEndian.PutUint64(e[deModified:], 0x07E8_03_0F_0C_22_38_00)
+		copy(e[deName:], name)
+		return e
+	}
+
+	files := map[string][]byte{
+		"icon.sys": bytes.Repeat([]byte("PS2D"), 241),
+		"DATA":     make([]byte, 2500),
+	}
+	for i := range files["DATA"] {
+		files["DATA"][i] = byte(i * 7)
+	}
+	const dir = ModeDir | ModeRead | ModeWrite | ModeExecute
+	const file = ModeFile | ModeRead | ModeWrite | ModeExecute
+
+	root := bytes.Join([][]byte{
+		entry(".", dir, 3, 0),
+		entry("..", dir, 0, 0),
+		entry("BESLES-12345", dir, 4, 1),
+	}, nil)
+	write(root, 0, 5)
+	save := bytes.Join([][]byte{
+		entry(".", dir, 4, 1),
+		entry("..", dir, 0, 0),
+		entry("icon.sys", file, uint32(len(files["icon.sys"])), 3),
+		entry("DATA", file, uint32(len(files["DATA"])), 6),
+	}, nil)
+	write(save, 1, 2)
+	write(files["icon.sys"], 3)
+	write(files["DATA"], 6, 4, 7)
+
+	dev, err := NewImageFile(bytes.NewReader(img), int64(len(img)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := Open(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs, files
+}
+
+func TestSaves(t *testing.T) {
+	fs, _ := saveCard(t)
+	if v := fs.Superblock().Version; v != "1.2.0.0" {
+		t.Errorf("version %q", v)
+	}
+	if err := fs.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+
+	saves, err := fs.Saves()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saves) != 1 || saves[0].Name != "BESLES-12345" || !saves[0].IsDir() || saves[0].Cluster != 1 {
+		t.Fatalf("Saves: %+v", saves)
+	}
+	want := time.Date(2024, 3, 15, 12, 34, 56, 0, cardZone)
+	if !saves[0].Modified.Equal(want) {
+		t.Errorf("modified %v, want %v", saves[0].Modified, want)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	fs, files := saveCard(t)
+	entries, err := fs.ReadDir("/BESLES-12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"icon.sys", "DATA"}
+	if len(entries) != len(names) {
+		t.Fatalf("ReadDir: %+v", entries)
+	}
+	for i, e := range entries {
+		if e.Name != names[i] || e.IsDir() || e.Size != uint32(len(files[e.Name])) {
+			t.Errorf("entry %d: %s, mode %04X, %d bytes", i, e.Name, e.Mode, e.Size)
+		}
+	}
+
+	if _, err := fs.ReadDir("/NOSAVE"); err != ErrNotFound {
+		t.Errorf("ReadDir of a missing save: %v, want %v", err, ErrNotFound)
+	}
+	if _, err := fs.ReadDir("/BESLES-12345/DATA"); err != ErrNotDir {
+		t.Errorf("ReadDir of a file: %v, want %v", err, ErrNotDir)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	fs, files := saveCard(t)
+	for name, want := range files {
+		got, err := fs.ReadFile("BESLES-12345/" + name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: contents differ", name)
+		}
+	}
+
+	if _, err := fs.ReadFile("/BESLES-12345"); err != ErrIsDir {
+		t.Errorf("ReadFile of a save: %v, want %v", err, ErrIsDir)
+	}
+	if _, err := fs.ReadFile("/BESLES-12345/icon.ico"); err != ErrNotFound {
+		t.Errorf("ReadFile of a missing file: %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	fs, files := saveCard(t)
+	got := map[string][]byte{}
+	err := fs.Extract("/BESLES-12345", func(e DirEntry, data []byte) error {
+		got[e.Name] = data
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(files) {
+		t.Errorf("extracted %d files, want %d", len(got), len(files))
+	}
+	for name, want := range files {
+		if !bytes.Equal(got[name], want) {
+			t.Errorf("%s: extracted contents differ", name)
+		}
+	}
+
+	// An error from fn stops the walk
+	stop := errors.New("stop")
+	n := 0
+	err = fs.Extract("/BESLES-12345", func(e DirEntry, data []byte) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("Extract returned %v after %d files", err, n)
+	}
+}
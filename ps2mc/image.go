@@ -0,0 +1,82 @@
+package ps2mc
+
+import (
+	"errors"
+	"io"
+)
+
+// Errors
+var (
+	ErrImageSize = errors.New("ps2mc: image size is not a whole number of pages")
+	ErrECC       = errors.New("ps2mc: uncorrectable ECC error")
+	ErrPage      = errors.New("ps2mc: page out of range")
+)
+
+// PageReader reads pages of a card, such as *gpsx.PS2MemoryCard or an
+// ImageFile.
+type PageReader interface {
+	ReadPage(page uint32, data []byte, spare []byte) error
+}
+
+// ImageFile reads pages from a card dump. Dumps either hold raw pages
+// followed by their spare areas (528 bytes per page) or the bare 512-byte
+// pages; the layout is told apart by the file size.
+type ImageFile struct {
+	r     io.ReaderAt
+	raw   bool
+	pages uint32
+}
+
+// NewImageFile creates an ImageFile reading a dump of the given size.
+func NewImageFile(r io.ReaderAt, size int64) (*ImageFile, error) {
+	f := &ImageFile{r: r}
+	switch {
+	case size > 0 && size%RawPageSize == 0 && (size/RawPageSize)%PagesPerBlock == 0:
+		f.raw = true
+		f.pages = uint32(size / RawPageSize)
+	case size > 0 && size%PageSize == 0:
+		f.pages = uint32(size / PageSize)
+	default:
+		return nil, ErrImageSize
+	}
+	return f, nil
+}
+
+// Pages returns the number of pages in the dump.
+func (f *ImageFile) Pages() uint32 {
+	return f.pages
+}
+
+// Raw returns true if the dump holds spare areas.
+func (f *ImageFile) Raw() bool {
+	return f.raw
+}
+
+// ReadPage reads a page and its spare area. Pages of raw dumps are
+// checked and corrected using their ECC; for bare dumps the spare area
+// is generated.
+func (f *ImageFile) ReadPage(page uint32, data []byte, spare []byte) error {
+	if page >= f.pages {
+		return ErrPage
+	}
+
+	if !f.raw {
+		if _, err := f.r.ReadAt(data[:PageSize], int64(page)*PageSize); err != nil {
+			return err
+		}
+		PageECC(data, spare)
+		return nil
+	}
+
+	off := int64(page) * RawPageSize
+	if _, err := f.r.ReadAt(data[:PageSize], off); err != nil {
+		return err
+	}
+	if _, err := f.r.ReadAt(spare[:SpareSize], off+PageSize); err != nil {
+		return err
+	}
+	if CheckPage(data, spare) == ECCFailed {
+		return ErrECC
+	}
+	return nil
+}
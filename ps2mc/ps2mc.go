@@ -0,0 +1,49 @@
+// Package ps2mc handles PS2 memory card pages and ECC.
+//
+// A PS2 card is NAND flash: 512-byte pages, each followed by a 16-byte
+// spare area, erased in blocks of 16 pages. The spare area holds a
+// Hamming code for each 128-byte chunk of the page, which corrects a
+// single flipped bit per chunk.
+//
+// On top of the pages sits the card filesystem: a superblock in page 0,
+// a FAT reached through two levels of indirect clusters, and directories
+// of 512-byte entries. FS reads it from any PageReader, so the same code
+// browses a card on the board or a dump file on a normal Go toolchain.
+package ps2mc
+
+// Standard 8MB card geometry
+const (
+	PageSize      = 512
+	SpareSize     = 16
+	RawPageSize   = PageSize + SpareSize
+	ChunkSize     = 128
+	ChunksPerPage = PageSize / ChunkSize
+	PagesPerBlock = 16
+)
+
+// Specs describes the geometry reported by a card.
+type Specs struct {
+	PageSize      uint16 // Bytes per page, without the spare area
+	PagesPerBlock uint16 // Pages per erase block
+	Pages         uint32 // Pages on the card
+}
+
+// DefaultSpecs is the geometry of a standard 8MB card.
+var DefaultSpecs = Specs{
+	PageSize:      PageSize,
+	PagesPerBlock: PagesPerBlock,
+	Pages:         16384,
+}
+
+// Size returns the capacity of the card in bytes, without spare areas.
+func (s Specs) Size() uint32 {
+	return uint32(s.PageSize) * s.Pages
+}
+
+// Blocks returns the number of erase blocks on the card.
+func (s Specs) Blocks() uint32 {
+	if s.PagesPerBlock == 0 {
+		return 0
+	}
+	return s.Pages / uint32(s.PagesPerBlock)
+}
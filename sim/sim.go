@@ -0,0 +1,61 @@
+// Package sim runs the driver against simulated pads, with no hardware.
+//
+// A Sim wires a gpsx.GPSX to two dualshock.Controller instances through a
+// bus.Loopback. The simulated pads implement the whole DualShock 2 command
+// set byte by byte (poll, config enter/exit, mode, status, motor mapping,
+// pressure), so the driver goes through the same exchanges as with a real
+// pad. Scripts set buttons and sticks on the pads, poll, and check what
+// the driver reports:
+//
+//	s := sim.New(gpsx.PS2)
+//	s.Press(gpsx.Pad1, gpsx.ButtonCross)
+//	s.Poll(gpsx.Pad1)
+//	if !s.PSX.Pressed(gpsx.Pad1, gpsx.ButtonCross) {
+//	    ...
+//	}
+package sim
+
+import (
+	"gpsx"
+	"gpsx/bus"
+	"gpsx/dualshock"
+)
+
+// Sim is a driver wired to two simulated pads.
+type Sim struct {
+	PSX  *gpsx.GPSX
+	Pads [2]*dualshock.Controller
+	Bus  *bus.Loopback
+}
+
+// New creates a Sim with a pad in each slot. More devices, such as a
+// ps1mc.Emulator, can be plugged in with Bus.Attach.
+func New(psxType uint8) *Sim {
+	s := &Sim{Bus: bus.NewLoopback()}
+	for i := range s.Pads {
+		s.Pads[i] = dualshock.New()
+		s.Bus.Attach(uint8(i), s.Pads[i])
+	}
+	s.PSX = gpsx.NewWithTransport(psxType, s.Bus)
+	return s
+}
+
+// Press holds btn down on the simulated pad.
+func (s *Sim) Press(pad uint8, btn gpsx.Button) {
+	s.Pads[pad].SetButton(btn, true)
+}
+
+// Release lets go of btn on the simulated pad.
+func (s *Sim) Release(pad uint8, btn gpsx.Button) {
+	s.Pads[pad].SetButton(btn, false)
+}
+
+// Sticks sets the simulated pad's stick positions.
+func (s *Sim) Sticks(pad uint8, rx, ry, lx, ly uint8) {
+	s.Pads[pad].SetSticks(rx, ry, lx, ly)
+}
+
+// Poll makes the driver poll the pad, as the main loop would.
+func (s *Sim) Poll(pad uint8) {
+	s.PSX.UpdateState(pad)
+}
@@ -0,0 +1,99 @@
+//go:build tinygo
+
+package gpsx
+
+import (
+	"machine"
+	"time"
+
+	"gpsx/bus"
+)
+
+// SnifferPinConfig holds the pins tapping a console-controller link.
+// All of them are inputs: the sniffer never drives the bus.
+type SnifferPinConfig struct {
+	ATT machine.Pin // Attention
+	CLK machine.Pin // Clock
+	CMD machine.Pin // Command, console to device
+	DAT machine.Pin // Data, device to console
+	ACK machine.Pin // Acknowledge (optional)
+}
+
+// Sniffer passively records the frames a console exchanges with its
+// controllers and memory cards. The lines are sampled in a busy loop and
+// every change is fed to a bus.Decoder, which rebuilds the bytes and
+// checks the timing.
+type Sniffer struct {
+	pins  SnifferPinConfig
+	start time.Time
+	dec   *bus.Decoder
+}
+
+// NewSniffer creates a Sniffer on the given pins.
+func NewSniffer(pins SnifferPinConfig) *Sniffer {
+	s := &Sniffer{
+		pins:  pins,
+		start: time.Now(),
+		dec:   bus.NewDecoder(),
+	}
+	s.dec.NoACK = pins.ACK == 0
+
+	s.pins.ATT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	s.pins.CLK.Configure(machine.PinConfig{Mode: machine.PinInput})
+	s.pins.CMD.Configure(machine.PinConfig{Mode: machine.PinInput})
+	s.pins.DAT.Configure(machine.PinConfig{Mode: machine.PinInput})
+	if s.pins.ACK != 0 {
+		s.pins.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
+	}
+	return s
+}
+
+// Next waits for the console to select a device and records the frame.
+// It returns false if no frame started within timeout; a zero timeout
+// waits forever. The frame's slices are reused by the next call.
+//
+// The console port does not say which slot ATT belongs to, so Pad is
+// always 0. Without an ACK pin every byte reads as unacknowledged.
+func (s *Sniffer) Next(timeout time.Duration) (bus.Frame, bool) {
+	deadline := time.Now().Add(timeout)
+	for s.pins.ATT.Get() {
+		if timeout > 0 && time.Now().After(deadline) {
+			return bus.Frame{}, false
+		}
+	}
+
+	// Only changes are timed: reading the clock is slow next to the pins
+	last := s.sample()
+	first := last
+	first.Time = time.Since(s.start)
+	s.dec.Feed(first)
+	for {
+		cur := s.sample()
+		if cur == last {
+			continue
+		}
+		last = cur
+		cur.Time = time.Since(s.start)
+		if s.dec.Feed(cur) {
+			return s.dec.Frame(), true
+		}
+	}
+}
+
+// Violations returns the protocol and timing faults found in the frame
+// last returned by Next.
+func (s *Sniffer) Violations() []bus.Violation {
+	return s.dec.Violations()
+}
+
+// sample reads every line, leaving Time unset. Without an ACK pin, ACK
+// reads as idle high.
+func (s *Sniffer) sample() bus.Sample {
+	return bus.Sample{
+		ATT: s.pins.ATT.Get(),
+		CLK: s.pins.CLK.Get(),
+		CMD: s.pins.CMD.Get(),
+		DAT: s.pins.DAT.Get(),
+		ACK: s.pins.ACK == 0 || s.pins.ACK.Get(),
+	}
+}
@@ -0,0 +1,202 @@
+package gpsx
+
+import (
+	"strconv"
+	"time"
+)
+
+// Buttons is a set of buttons as a bitmask, 1 = pressed. Bits 0-7 come
+// from byte 3 of the poll response and bits 8-15 from byte 4, so bit
+// order follows the protocol: Select, L3, R3, Start, Up, Right, Down,
+// Left, L2, R2, L1, R1, Triangle, Circle, Cross, Square.
+type Buttons uint16
+
+// AllButtons is the set of every digital button.
+const AllButtons Buttons = 0xFFFF
+
+// buttonTable lists every button in bit order, with its name.
+var buttonTable = [16]struct {
+	btn  Button
+	name string
+}{
+	{ButtonSelect, "Select"}, {ButtonStickLeft, "L3"}, {ButtonStickRight, "R3"}, {ButtonStart, "Start"},
+	{ButtonUp, "Up"}, {ButtonRight, "Right"}, {ButtonDown, "Down"}, {ButtonLeft, "Left"},
+	{ButtonL2, "L2"}, {ButtonR2, "R2"}, {ButtonL1, "L1"}, {ButtonR1, "R1"},
+	{ButtonTriangle, "Triangle"}, {ButtonCircle, "Circle"}, {ButtonCross, "Cross"}, {ButtonSquare, "Square"},
+}
+
+// Mask returns the set holding only this button.
+func (btn Button) Mask() Buttons {
+	return Buttons(btn.bitMask) << ((btn.byteIndex - 3) * 8)
+}
+
+// String returns the button's name.
+func (btn Button) String() string {
+	for _, b := range buttonTable {
+		if b.btn == btn {
+			return b.name
+		}
+	}
+	return "Button(" + strconv.Itoa(int(btn.byteIndex)) + "," + strconv.Itoa(int(btn.bitMask)) + ")"
+}
+
+// ButtonsOf returns the set of the given buttons.
+func ButtonsOf(btns ...Button) Buttons {
+	var b Buttons
+	for _, btn := range btns {
+		b |= btn.Mask()
+	}
+	return b
+}
+
+// ParseButtons returns the buttons held in a raw poll response, such as
+// a frame recorded by a Sniffer.
+func ParseButtons(resp []byte) Buttons {
+	if len(resp) < 5 {
+		return 0
+	}
+	// Active LOW on the wire
+	return ^(Buttons(resp[3]) | Buttons(resp[4])<<8)
+}
+
+// Has reports whether btn is in the set.
+func (b Buttons) Has(btn Button) bool {
+	return b&btn.Mask() != 0
+}
+
+// All reports whether every button of other is in the set.
+func (b Buttons) All(other Buttons) bool {
+	return b&other == other
+}
+
+// Any reports whether any button of other is in the set.
+func (b Buttons) Any(other Buttons) bool {
+	return b&other != 0
+}
+
+// Union returns the buttons in either set.
+func (b Buttons) Union(other Buttons) Buttons {
+	return b | other
+}
+
+// Intersect returns the buttons in both sets.
+func (b Buttons) Intersect(other Buttons) Buttons {
+	return b & other
+}
+
+// Without returns the set minus the buttons of other.
+func (b Buttons) Without(other Buttons) Buttons {
+	return b &^ other
+}
+
+// Count returns the number of buttons in the set.
+func (b Buttons) Count() int {
+	n := 0
+	for ; b != 0; b &= b - 1 {
+		n++
+	}
+	return n
+}
+
+// Each calls fn for every button in the set, in bit order.
+func (b Buttons) Each(fn func(Button)) {
+	for i, e := range buttonTable {
+		if b&(1<<i) != 0 {
+			fn(e.btn)
+		}
+	}
+}
+
+// String lists the buttons in the set joined by "+", or "none".
+func (b Buttons) String() string {
+	if b == 0 {
+		return "none"
+	}
+	s := ""
+	for i, e := range buttonTable {
+		if b&(1<<i) != 0 {
+			if s != "" {
+				s += "+"
+			}
+			s += e.name
+		}
+	}
+	return s
+}
+
+// State is a snapshot of a pad after a poll. It is a plain value: it can
+// be copied, compared with == and handed to another goroutine.
+type State struct {
+	ID      uint8   // Device ID (see DeviceID)
+	Buttons Buttons // Buttons held
+
+	// Sticks, 0-255 with 0x80 at rest. Only valid in analog mode.
+	RightX, RightY uint8
+	LeftX, LeftY   uint8
+}
+
+// IsDown reports whether btn is held.
+func (s State) IsDown(btn Button) bool {
+	return s.Buttons.Has(btn)
+}
+
+// IsAnalog reports whether the pad was in analog mode (see GPSX.IsAnalog).
+func (s State) IsAnalog() bool {
+	return isAnalogID(s.ID)
+}
+
+// IsDigital reports whether the pad was in digital mode.
+func (s State) IsDigital() bool {
+	return s.ID&0xF0 == DeviceDigital&0xF0
+}
+
+// IsFlightStick reports whether the pad was a PS1 Analog Joystick.
+func (s State) IsFlightStick() bool {
+	return s.ID == DeviceFlightStick
+}
+
+// String formats the state for logs.
+func (s State) String() string {
+	str := "id " + hexByte(s.ID) + " " + s.Buttons.String()
+	if s.IsAnalog() {
+		str += " R(" + strconv.Itoa(int(s.RightX)) + "," + strconv.Itoa(int(s.RightY)) + ")" +
+			" L(" + strconv.Itoa(int(s.LeftX)) + "," + strconv.Itoa(int(s.LeftY)) + ")"
+	}
+	return str
+}
+
+// State returns a snapshot of the pad as of the last UpdateState.
+func (g *GPSX) State(pad uint8) State {
+	k := &g.keyState[pad][stateCurrent]
+	return State{
+		ID:      k[1],
+		Buttons: ParseButtons(k[:]),
+		RightX:  k[5],
+		RightY:  k[6],
+		LeftX:   k[7],
+		LeftY:   k[8],
+	}
+}
+
+// Poll updates the pad like UpdateState and returns its new state.
+func (g *GPSX) Poll(pad uint8) State {
+	g.UpdateState(pad)
+	return g.State(pad)
+}
+
+// PollTime returns when the last UpdateState of pad was made.
+func (g *GPSX) PollTime(pad uint8) time.Time {
+	return g.holds[pad].now
+}
+
+// isAnalogID reports whether a device ID is one of the analog modes.
+func isAnalogID(id uint8) bool {
+	id &= 0xF0
+	return id == DeviceAnalog&0xF0 || id == DeviceFlightStick&0xF0
+}
+
+// hexByte formats b as two hex digits.
+func hexByte(b uint8) string {
+	const digits = "0123456789ABCDEF"
+	return string([]byte{digits[b>>4], digits[b&0x0F]})
+}
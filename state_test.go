@@ -0,0 +1,113 @@
+package gpsx_test
+
+import (
+	"testing"
+
+	"gpsx"
+	"gpsx/sim"
+)
+
+func TestButtonSet(t *testing.T) {
+	cross := gpsx.ButtonCross.Mask()
+	dpad := gpsx.ButtonsOf(gpsx.ButtonUp, gpsx.ButtonRight, gpsx.ButtonDown, gpsx.ButtonLeft)
+	b := gpsx.ButtonsOf(gpsx.ButtonCross, gpsx.ButtonUp)
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"Has", b.Has(gpsx.ButtonUp), true},
+		{"Has other", b.Has(gpsx.ButtonDown), false},
+		{"All", b.All(cross), true},
+		{"All other", b.All(dpad), false},
+		{"Any", b.Any(dpad), true},
+		{"Any none", b.Any(gpsx.ButtonStart.Mask()), false},
+		{"Union", b.Union(dpad), dpad | cross},
+		{"Intersect", b.Intersect(dpad), gpsx.ButtonUp.Mask()},
+		{"Without", b.Without(dpad), cross},
+		{"Count", dpad.Count(), 4},
+		{"Count all", gpsx.AllButtons.Count(), 16},
+		{"Mask bit", gpsx.ButtonSquare.Mask(), gpsx.Buttons(0x8000)},
+		{"String", b.String(), "Up+Cross"},
+		{"String none", gpsx.Buttons(0).String(), "none"},
+		{"Button String", gpsx.ButtonStickLeft.String(), "L3"},
+		{"Button String unknown", gpsx.Button{}.String(), "Button(0,0)"},
+		{"ParseButtons", gpsx.ParseButtons([]byte{0xFF, 0x41, 0x5A, 0xFE, 0xBF}), gpsx.ButtonsOf(gpsx.ButtonSelect, gpsx.ButtonCross)},
+		{"ParseButtons none", gpsx.ParseButtons([]byte{0xFF, 0x41, 0x5A, 0xFF, 0xFF}), gpsx.Buttons(0)},
+		{"ParseButtons short", gpsx.ParseButtons([]byte{0xFF, 0x41, 0x5A, 0x00}), gpsx.Buttons(0)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestButtonsEach(t *testing.T) {
+	var got []gpsx.Button
+	gpsx.ButtonsOf(gpsx.ButtonSquare, gpsx.ButtonSelect, gpsx.ButtonL2).Each(func(btn gpsx.Button) {
+		got = append(got, btn)
+	})
+	want := []gpsx.Button{gpsx.ButtonSelect, gpsx.ButtonL2, gpsx.ButtonSquare}
+	if len(got) != len(want) {
+		t.Fatalf("Each gave %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Each %d: %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Every button of AllButtons, in bit order
+	i := 0
+	gpsx.AllButtons.Each(func(btn gpsx.Button) {
+		if btn.Mask() != 1<<i {
+			t.Errorf("Each %d: %v has mask %04X", i, btn, uint16(btn.Mask()))
+		}
+		i++
+	})
+	if i != 16 {
+		t.Errorf("Each over AllButtons ran %d times", i)
+	}
+}
+
+func TestStateSnapshot(t *testing.T) {
+	s := sim.New(gpsx.PS2)
+	s.Press(gpsx.Pad1, gpsx.ButtonCircle)
+	s.Press(gpsx.Pad1, gpsx.ButtonR1)
+	st := s.PSX.Poll(gpsx.Pad1)
+
+	if st.ID != gpsx.DeviceDigital || !st.IsDigital() || st.IsAnalog() {
+		t.Errorf("digital pad reads id %02X", st.ID)
+	}
+	for _, btn := range []gpsx.Button{gpsx.ButtonCircle, gpsx.ButtonR1, gpsx.ButtonCross, gpsx.ButtonStart} {
+		if st.IsDown(btn) != s.PSX.IsDown(gpsx.Pad1, btn) {
+			t.Errorf("State.IsDown(%v) = %v, GPSX.IsDown says otherwise", btn, st.IsDown(btn))
+		}
+	}
+	if want := "id 41 R1+Circle"; st.String() != want {
+		t.Errorf("String %q, want %q", st.String(), want)
+	}
+
+	s.PSX.Mode(gpsx.Pad1, gpsx.ModeAnalog, gpsx.ModeLock)
+	s.Release(gpsx.Pad1, gpsx.ButtonCircle)
+	s.Sticks(gpsx.Pad1, 0x10, 0x20, 0xE0, 0xF0)
+	st = s.PSX.Poll(gpsx.Pad1)
+	if st != s.PSX.State(gpsx.Pad1) {
+		t.Error("Poll and State disagree")
+	}
+	sticks := [4]uint8{st.RightX, st.RightY, st.LeftX, st.LeftY}
+	want := [4]uint8{
+		s.PSX.AnalogRightX(gpsx.Pad1), s.PSX.AnalogRightY(gpsx.Pad1),
+		s.PSX.AnalogLeftX(gpsx.Pad1), s.PSX.AnalogLeftY(gpsx.Pad1),
+	}
+	if sticks != want || sticks != [4]uint8{0x10, 0x20, 0xE0, 0xF0} {
+		t.Errorf("State sticks % X, accessors % X", sticks, want)
+	}
+	if !st.IsAnalog() || st.IsDigital() || st.IsAnalog() != s.PSX.IsAnalog(gpsx.Pad1) {
+		t.Errorf("analog pad reads id %02X", st.ID)
+	}
+	if want := "id 73 R1 R(16,32) L(224,240)"; st.String() != want {
+		t.Errorf("String %q, want %q", st.String(), want)
+	}
+}
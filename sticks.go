@@ -0,0 +1,196 @@
+package gpsx
+
+// Stick selects one of the analog sticks.
+type Stick uint8
+
+// Sticks
+const (
+	StickLeft Stick = iota
+	StickRight
+)
+
+// DeadzoneMode selects how the deadzone of a stick is shaped.
+type DeadzoneMode uint8
+
+// Deadzone modes
+const (
+	// DeadzoneNone applies no deadzone.
+	DeadzoneNone DeadzoneMode = iota
+
+	// DeadzoneAxial zeroes each axis on its own, which makes it easy to
+	// hold a pure horizontal or vertical direction but snaps diagonals
+	// near the centre onto the axes.
+	DeadzoneAxial
+
+	// DeadzoneRadial zeroes the stick inside a circle and passes it
+	// through unchanged outside, so output jumps at the edge.
+	DeadzoneRadial
+
+	// DeadzoneScaledRadial zeroes the stick inside a circle and rescales
+	// the rest, so output grows smoothly from zero at the edge.
+	DeadzoneScaledRadial
+)
+
+// Curve selects how stick deflection maps to output.
+type Curve uint8
+
+// Response curves
+const (
+	CurveLinear      Curve = iota
+	CurveExponential       // Blend of linear and cubic, set by Expo
+	CurveTable             // Piecewise linear through Table
+)
+
+// StickMax is the largest value returned by Stick, in either direction.
+const StickMax = 127
+
+// StickConfig sets how Stick processes one stick. Distances are in the
+// centred units Stick returns, 0 to StickMax.
+type StickConfig struct {
+	Mode     DeadzoneMode
+	Deadzone uint8 // Inner deadzone radius (or half width, for axial)
+	Outer    uint8 // Deflection read as full scale, 0 = StickMax
+
+	Curve Curve
+	Expo  uint8      // CurveExponential: percent of cubic, 0-100
+	Table *[17]uint8 // CurveTable: output for inputs 0, 8, 16 ... 128
+}
+
+// DefaultStickConfig passes the stick through unprocessed.
+var DefaultStickConfig = StickConfig{}
+
+// SetStickConfig sets the processing of one stick of pad for Stick.
+func (g *GPSX) SetStickConfig(pad uint8, stick Stick, cfg StickConfig) {
+	g.sticks[pad][stick&1] = cfg
+}
+
+// Stick returns one stick of pad as signed values centred on zero, from
+// -StickMax to StickMax, after the deadzone and response curve set with
+// SetStickConfig. Axes keep the direction of the raw values: negative X
+// is left and negative Y is up. Only valid in analog mode.
+func (g *GPSX) Stick(pad uint8, stick Stick) (x, y int8) {
+	k := &g.keyState[pad][stateCurrent]
+	rx, ry := k[7], k[8]
+	if stick&1 == StickRight {
+		rx, ry = k[5], k[6]
+	}
+	return g.sticks[pad][stick&1].apply(centre(rx), centre(ry))
+}
+
+// centre converts a raw axis value to a signed one around 0x80.
+func centre(v uint8) int32 {
+	c := int32(v) - 0x80
+	if c < -StickMax {
+		c = -StickMax
+	}
+	return c
+}
+
+// apply runs a centred stick position through the configuration.
+func (c *StickConfig) apply(x, y int32) (int8, int8) {
+	outer := int32(c.Outer)
+	if outer == 0 || outer > StickMax {
+		outer = StickMax
+	}
+	dz := int32(c.Deadzone)
+	if dz >= outer {
+		dz = outer - 1
+	}
+
+	if c.Mode != DeadzoneRadial && c.Mode != DeadzoneScaledRadial {
+		if c.Mode != DeadzoneAxial {
+			dz = 0
+		}
+		return int8(c.axis(x, dz, outer)), int8(c.axis(y, dz, outer))
+	}
+
+	r := isqrt(x*x + y*y)
+	if r <= dz {
+		return 0, 0
+	}
+	base := int32(0)
+	if c.Mode == DeadzoneScaledRadial {
+		base = dz
+	}
+	m := c.curve(rescale(r, base, outer))
+
+	// Scale the vector to the new length, then clip to the square the
+	// output range allows
+	x = clamp(x * m / r)
+	y = clamp(y * m / r)
+	return int8(x), int8(y)
+}
+
+// axis applies an axial deadzone and the curve to one axis.
+func (c *StickConfig) axis(v, dz, outer int32) int32 {
+	m := v
+	if m < 0 {
+		m = -m
+	}
+	if m <= dz {
+		return 0
+	}
+	m = c.curve(rescale(m, dz, outer))
+	if v < 0 {
+		return -m
+	}
+	return m
+}
+
+// curve applies the response curve to a magnitude from 0 to StickMax.
+func (c *StickConfig) curve(m int32) int32 {
+	switch c.Curve {
+	case CurveExponential:
+		e := int32(c.Expo)
+		if e > 100 {
+			e = 100
+		}
+		cubic := m * m * m / (StickMax * StickMax)
+		return (m*(100-e) + cubic*e) / 100
+	case CurveTable:
+		if c.Table == nil {
+			return m
+		}
+		i := m / 8
+		if i >= 16 {
+			return clamp(int32(c.Table[16]))
+		}
+		a, b := int32(c.Table[i]), int32(c.Table[i+1])
+		return clamp(a + (b-a)*(m%8)/8)
+	}
+	return m
+}
+
+// rescale maps m from [dz, outer] onto [0, StickMax], saturating above.
+func rescale(m, dz, outer int32) int32 {
+	if m >= outer {
+		return StickMax
+	}
+	return (m - dz) * StickMax / (outer - dz)
+}
+
+// clamp limits v to the range of a stick value.
+func clamp(v int32) int32 {
+	if v > StickMax {
+		return StickMax
+	}
+	if v < -StickMax {
+		return -StickMax
+	}
+	return v
+}
+
+// isqrt returns the integer square root of v.
+func isqrt(v int32) int32 {
+	if v <= 0 {
+		return 0
+	}
+	r := v
+	for {
+		n := (r + v/r) / 2
+		if n >= r {
+			return r
+		}
+		r = n
+	}
+}
@@ -0,0 +1,72 @@
+package gpsx
+
+import "testing"
+
+func TestStickApply(t *testing.T) {
+	table := [17]uint8{0, 4, 8, 16, 24, 32, 40, 48, 56, 64, 72, 80, 88, 96, 104, 120, 127}
+
+	tests := []struct {
+		name   string
+		cfg    StickConfig
+		x, y   int32
+		wx, wy int8
+	}{
+		{"none centre", StickConfig{}, 0, 0, 0, 0},
+		{"none passes", StickConfig{}, -50, 90, -50, 90},
+		{"axial centre", StickConfig{Mode: DeadzoneAxial, Deadzone: 20}, 0, 0, 0, 0},
+		{"radial centre", StickConfig{Mode: DeadzoneRadial, Deadzone: 20}, 0, 0, 0, 0},
+		{"scaled centre", StickConfig{Mode: DeadzoneScaledRadial, Deadzone: 20}, 0, 0, 0, 0},
+
+		// A diagonal inside the axial band on both axes, outside the circle
+		{"axial diagonal", StickConfig{Mode: DeadzoneAxial, Deadzone: 20}, 18, 18, 0, 0},
+		{"radial diagonal", StickConfig{Mode: DeadzoneRadial, Deadzone: 20}, 18, 18, 18, 18},
+		// Axial snaps a shallow diagonal onto the axis, radial keeps it
+		{"axial snaps", StickConfig{Mode: DeadzoneAxial, Deadzone: 20}, 60, 15, 47, 0},
+		{"radial keeps", StickConfig{Mode: DeadzoneRadial, Deadzone: 20}, 60, 15, 60, 15},
+
+		// Just past the edge: radial jumps to the raw value, scaled
+		// radial starts from zero
+		{"radial edge", StickConfig{Mode: DeadzoneRadial, Deadzone: 20}, 21, 0, 21, 0},
+		{"scaled edge", StickConfig{Mode: DeadzoneScaledRadial, Deadzone: 20}, 21, 0, 1, 0},
+		{"scaled inside", StickConfig{Mode: DeadzoneScaledRadial, Deadzone: 20}, 20, 0, 0, 0},
+		{"scaled full", StickConfig{Mode: DeadzoneScaledRadial, Deadzone: 20}, -127, 0, -127, 0},
+
+		{"outer axial", StickConfig{Mode: DeadzoneAxial, Outer: 100}, 110, -100, 127, -127},
+		{"outer radial", StickConfig{Mode: DeadzoneRadial, Outer: 100}, 0, -105, 0, -127},
+		{"outer diagonal", StickConfig{Mode: DeadzoneRadial, Outer: 100}, 80, 80, 89, 89},
+		{"outer none", StickConfig{Outer: 64}, 64, 32, 127, 63},
+
+		{"expo 0", StickConfig{Curve: CurveExponential}, 64, 0, 64, 0},
+		{"expo 100", StickConfig{Curve: CurveExponential, Expo: 100}, 64, 0, 16, 0},
+		{"expo 50", StickConfig{Curve: CurveExponential, Expo: 50}, 64, -64, 40, -40},
+		{"expo full", StickConfig{Curve: CurveExponential, Expo: 50}, 127, 0, 127, 0},
+		{"expo over 100", StickConfig{Curve: CurveExponential, Expo: 200}, 64, 0, 16, 0},
+
+		{"table entry", StickConfig{Curve: CurveTable, Table: &table}, 16, 0, 8, 0},
+		{"table midpoint", StickConfig{Curve: CurveTable, Table: &table}, 116, 0, 112, 0},
+		{"table low end", StickConfig{Curve: CurveTable, Table: &table}, 1, 0, 0, 0},
+		{"table high end", StickConfig{Curve: CurveTable, Table: &table}, -127, 0, -126, 0},
+		{"table missing", StickConfig{Curve: CurveTable}, 50, 0, 50, 0},
+	}
+	for _, tt := range tests {
+		x, y := tt.cfg.apply(tt.x, tt.y)
+		if x != tt.wx || y != tt.wy {
+			t.Errorf("%s: (%d,%d) -> (%d,%d), want (%d,%d)", tt.name, tt.x, tt.y, x, y, tt.wx, tt.wy)
+		}
+	}
+}
+
+func TestScaledRadialContinuous(t *testing.T) {
+	cfg := StickConfig{Mode: DeadzoneScaledRadial, Deadzone: 30}
+	prev := int8(0)
+	for r := int32(0); r <= StickMax; r++ {
+		x, _ := cfg.apply(r, 0)
+		if x < prev || x-prev > 2 {
+			t.Fatalf("output steps from %d to %d at %d", prev, x, r)
+		}
+		prev = x
+	}
+	if prev != StickMax {
+		t.Errorf("full deflection gives %d, want %d", prev, StickMax)
+	}
+}